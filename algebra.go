@@ -0,0 +1,143 @@
+package bf
+
+import "math"
+
+/*
+Difference creates a new BloomFilter which contains bits of a which are not
+set in b, given filters are not changed. Items of a which share any bit with
+items of b are not found in the result. They must use the same Storage and Hash.
+*/
+func Difference(a, b BloomFilter) (BloomFilter, error) {
+	return combine(a, b, (*bloomFilter).difference)
+}
+
+/*
+SymmetricDifference creates a new BloomFilter which contains bits set in
+exactly one of a and b, given filters are not changed. They must use the same
+Storage and Hash.
+*/
+func SymmetricDifference(a, b BloomFilter) (BloomFilter, error) {
+	return combine(a, b, (*bloomFilter).xor)
+}
+
+/*
+Jaccard estimates the Jaccard similarity |A ∩ B| / |A ∪ B| of items added into
+the given filters from their number of set bits, given filters are not changed.
+It returns 1 if both filters are empty. They must use the same Storage and Hash.
+*/
+func Jaccard(a, b BloomFilter) (float64, error) {
+	if err := Compatible(a, b); err != nil {
+		return 0, err
+	}
+
+	cfg := a.Config()
+	if cfg == nil {
+		return 0, ErrNilConfig
+	}
+
+	sources := stableAll([]BloomFilter{a, b})
+	as, bs := sources[0].Storage(), sources[1].Storage()
+	m := as.Capacity()
+	k := cfg.NumberOfHashFunctions()
+
+	union := estimateCount(countUnionBits(as, bs), m, k)
+	if union == 0 {
+		return 1, nil
+	}
+
+	intersect := estimateCount(countBits(as), m, k) + estimateCount(countBits(bs), m, k) - union
+	if intersect < 0 {
+		return 0, nil
+	}
+	return math.Min(intersect/union, 1), nil
+}
+
+func combine(a, b BloomFilter, op func(*bloomFilter, ReadOnlyFilter)) (BloomFilter, error) {
+	if err := Compatible(a, b); err != nil {
+		return nil, err
+	}
+
+	r, err := a.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	target, ok := innerBloomFilter(r).(*bloomFilter)
+	if !ok {
+		return nil, ErrUnsupportedBloomFilter
+	}
+
+	op(target, stable(b))
+	return r, nil
+}
+
+func (b *bloomFilter) difference(other ReadOnlyFilter) {
+	defer b.merged()
+	if bd, ok := b.storage.(BatchDifference); ok {
+		bd.Difference(other.Storage())
+		return
+	}
+
+	oStorage := other.Storage()
+	for i := uint32(0); i < oStorage.Capacity(); i++ {
+		if oStorage.Get(i) {
+			b.storage.Clear(i)
+		}
+	}
+}
+
+func (b *bloomFilter) xor(other ReadOnlyFilter) {
+	defer b.merged()
+	if bx, ok := b.storage.(BatchXor); ok {
+		bx.Xor(other.Storage())
+		return
+	}
+
+	oStorage := other.Storage()
+	for i := uint32(0); i < oStorage.Capacity(); i++ {
+		if b.storage.Get(i) != oStorage.Get(i) {
+			b.storage.Set(i)
+		} else {
+			b.storage.Clear(i)
+		}
+	}
+}
+
+func countBits(s Storage) uint32 {
+	if b, ok := s.(*bitset); ok {
+		return b.count()
+	}
+
+	var result uint32
+	for i := uint32(0); i < s.Capacity(); i++ {
+		if s.Get(i) {
+			result++
+		}
+	}
+	return result
+}
+
+func countUnionBits(a, b Storage) uint32 {
+	ab, aOk := a.(*bitset)
+	bb, bOk := b.(*bitset)
+	if aOk && bOk {
+		return orCountWords(ab.words(), bb.words())
+	}
+
+	var result uint32
+	for i := uint32(0); i < a.Capacity(); i++ {
+		if a.Get(i) || b.Get(i) {
+			result++
+		}
+	}
+	return result
+}
+
+// estimateCount estimates number of items from number of set bits x by
+// n = -m/k * ln(1 - x/m).
+func estimateCount(x, m uint32, k byte) float64 {
+	if x >= m {
+		x = m - 1
+	}
+	return -float64(m) / float64(k) * math.Log(1-float64(x)/float64(m))
+}
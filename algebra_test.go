@@ -0,0 +1,161 @@
+package bf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func makeFilterForAlgebraTest(from, to int, opts ...OptionFunc) BloomFilter {
+	f := Must(WithAccuracy(0.01, 10_000), opts...)
+	for i := from; i < to; i++ {
+		f.Add([]byte(fmt.Sprintf("item-%d", i)))
+	}
+	return f
+}
+
+func TestAlgebra_ReturnsErrIfFiltersAreIncompatible(t *testing.T) {
+	a := Must(WithCapacity(1000, 3))
+	cases := []struct {
+		name     string
+		b        BloomFilter
+		expected error
+	}{
+		{name: "nil", b: nil, expected: ErrNilBloomFilter},
+		{name: "storage", b: Must(WithCapacity(999, 3)), expected: ErrStorageDifference},
+		{name: "hasher", b: Must(WithCapacity(1000, 3), WithFNV()), expected: ErrHasherDifference},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Difference(a, tc.b); !errors.Is(err, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+			if _, err := SymmetricDifference(a, tc.b); !errors.Is(err, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+			if _, err := Jaccard(a, tc.b); !errors.Is(err, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestDifference_SymmetricDifference(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []OptionFunc
+	}{
+		{name: "bitset"},
+		{name: "concurrent", opts: []OptionFunc{WithConcurrency()}},
+		{name: "custom storage", opts: []OptionFunc{WithStorage(sliceStorageFactory{})}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := Must(WithCapacity(4096, 3), tc.opts...)
+			b := Must(WithCapacity(4096, 3), tc.opts...)
+			a.Add([]byte("only-a"))
+			a.Add([]byte("shared"))
+			b.Add([]byte("only-b"))
+			b.Add([]byte("shared"))
+			before, _ := Marshal(a)
+
+			diff, err := Difference(a, b)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			xor, err := SymmetricDifference(a, b)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			if !diff.Exists([]byte("only-a")) || diff.Exists([]byte("only-b")) || diff.Exists([]byte("shared")) {
+				t.Errorf("unexpected difference")
+			}
+			if !xor.Exists([]byte("only-a")) || !xor.Exists([]byte("only-b")) || xor.Exists([]byte("shared")) {
+				t.Errorf("unexpected symmetric difference")
+			}
+			if diff.Count() != -1 || xor.Count() != -1 {
+				t.Errorf("expected count is -1")
+			}
+
+			after, _ := Marshal(a)
+			if string(before) != string(after) || a.Count() != 2 || b.Count() != 2 {
+				t.Errorf("expected given filters are not changed")
+			}
+		})
+	}
+}
+
+func TestDifference_SymmetricDifferenceOfDurableFilterWhileItIsWritten(t *testing.T) {
+	a := Must(WithCapacity(4096, 3))
+	d := openDurableForTest(t, t.TempDir())
+	defer d.Close()
+	a.Add([]byte("a"))
+	d.Add([]byte("d"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			d.Add([]byte(fmt.Sprintf("item-%d", i)))
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if _, err := Difference(a, d); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if _, err := SymmetricDifference(a, d); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	}
+	<-done
+
+	r, _ := SymmetricDifference(a, d)
+	if !r.Exists([]byte("a")) || !r.Exists([]byte("d")) {
+		t.Errorf("expected a and d exist")
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	cases := []struct {
+		name     string
+		a, b     BloomFilter
+		expected float64
+	}{
+		{name: "empty", a: makeFilterForAlgebraTest(0, 0), b: makeFilterForAlgebraTest(0, 0), expected: 1},
+		{name: "same", a: makeFilterForAlgebraTest(0, 1000), b: makeFilterForAlgebraTest(0, 1000), expected: 1},
+		{name: "disjoint", a: makeFilterForAlgebraTest(0, 1000), b: makeFilterForAlgebraTest(1000, 2000), expected: 0},
+		{name: "one third", a: makeFilterForAlgebraTest(0, 1000), b: makeFilterForAlgebraTest(500, 1500), expected: 1.0 / 3},
+		{name: "subset", a: makeFilterForAlgebraTest(0, 2000), b: makeFilterForAlgebraTest(0, 500), expected: 0.25},
+		{
+			name:     "custom storage",
+			a:        makeFilterForAlgebraTest(0, 1000, WithStorage(sliceStorageFactory{})),
+			b:        makeFilterForAlgebraTest(500, 1500, WithStorage(sliceStorageFactory{})),
+			expected: 1.0 / 3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Jaccard(tc.a, tc.b)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if math.Abs(result-tc.expected) > 0.03 {
+				t.Errorf("expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestEstimateCount(t *testing.T) {
+	if estimateCount(0, 1000, 3) != 0 {
+		t.Errorf("expected 0")
+	}
+	if math.IsInf(estimateCount(1000, 1000, 3), 0) {
+		t.Errorf("expected a finite estimation for a full storage")
+	}
+}
@@ -0,0 +1,102 @@
+package bf
+
+import "math/bits"
+
+const existsManyGroupSize = 512
+const existsManyBuckets = 256
+
+/*
+ExistsMany checks existence of many items, the result i is Exists of the item
+i. Indexes of a group of items are computed first, then grouped by memory
+region with a counting sort and probed in that order so independent memory
+loads overlap instead of waiting for each other. It is faster than repeated
+Exists on big filters when hashing is cheap compared with cache misses.
+*/
+func ExistsMany(f BloomFilter, items [][]byte) []bool {
+	result := make([]bool, len(items))
+	switch v := f.(type) {
+	case *bloomFilter:
+		v.existsMany(items, result)
+	case *concurrentBloomFilter:
+		v.mu.RLock()
+		defer v.mu.RUnlock()
+		v.filter.existsMany(items, result)
+	case *DurableBloomFilter:
+		v.mu.RLock()
+		defer v.mu.RUnlock()
+		v.filter.existsMany(items, result)
+	default:
+		for i, item := range items {
+			result[i] = f.Exists(item)
+		}
+	}
+	return result
+}
+
+// existsMany encodes a probe as index << 16 | position of the item in group.
+func (b *bloomFilter) existsMany(items [][]byte, result []bool) {
+	capacity := b.storage.Capacity()
+	shift := 0
+	if l := bits.Len32(capacity - 1); l > 8 {
+		shift = l - 8
+	}
+
+	var probes, grouped []uint64
+	for lo := 0; lo < len(items); lo += existsManyGroupSize {
+		hi := lo + existsManyGroupSize
+		if hi > len(items) {
+			hi = len(items)
+		}
+
+		probes = probes[:0]
+		for i := lo; i < hi; i++ {
+			result[i] = true
+			for _, key := range b.hasher.Hash(items[i], 1)[0] {
+				probes = append(probes, uint64(b.index(key))<<16|uint64(i-lo))
+			}
+		}
+
+		if cap(grouped) < len(probes) {
+			grouped = make([]uint64, len(probes))
+		}
+		grouped = groupProbes(grouped[:len(probes)], probes, shift+16)
+		b.probe(grouped, result[lo:hi])
+	}
+}
+
+func groupProbes(dst, src []uint64, shift int) []uint64 {
+	var offsets [existsManyBuckets + 1]int
+	for _, p := range src {
+		offsets[p>>shift+1]++
+	}
+	for i := 1; i <= existsManyBuckets; i++ {
+		offsets[i] += offsets[i-1]
+	}
+	for _, p := range src {
+		dst[offsets[p>>shift]] = p
+		offsets[p>>shift]++
+	}
+	return dst
+}
+
+func (b *bloomFilter) probe(probes []uint64, result []bool) {
+	// a released bitset has no words, Get finds no items of it
+	if bs, ok := b.storage.(*bitset); ok && !bs.released() {
+		for _, p := range probes {
+			if !result[p&0xffff] {
+				continue
+			}
+			n, m := bs.indexing(uint32(p >> 16))
+			if bs.word(n)&m == 0 {
+				result[p&0xffff] = false
+			}
+		}
+		return
+	}
+
+	for _, p := range probes {
+		if result[p&0xffff] && !b.storage.Get(uint32(p>>16)) {
+			result[p&0xffff] = false
+		}
+	}
+}
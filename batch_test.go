@@ -0,0 +1,53 @@
+package bf
+
+import (
+	"fmt"
+	"testing"
+)
+
+type wrappedBloomFilter struct {
+	BloomFilter
+}
+
+func TestExistsMany(t *testing.T) {
+	cases := []struct {
+		name string
+		f    BloomFilter
+	}{
+		{name: "bitset", f: Must(WithCapacity(1<<16, 3))},
+		{name: "small", f: Must(WithCapacity(100, 3))},
+		{name: "concurrent", f: Must(WithCapacity(1<<16, 3), WithConcurrency())},
+		{name: "custom storage", f: Must(WithCapacity(1<<16, 3), WithStorage(sliceStorageFactory{}))},
+		{name: "durable", f: openDurableForTest(t, t.TempDir())},
+		{name: "other implementation", f: wrappedBloomFilter{Must(WithCapacity(1<<16, 3))}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var items [][]byte
+			for i := 0; i < 2000; i++ {
+				item := []byte(fmt.Sprintf("item-%d", i))
+				if i%2 == 0 {
+					tc.f.Add(item)
+				}
+				items = append(items, item)
+			}
+
+			result := ExistsMany(tc.f, items)
+			if len(result) != len(items) {
+				t.Fatalf("expected %v results, got %v", len(items), len(result))
+			}
+			for i, item := range items {
+				if result[i] != tc.f.Exists(item) {
+					t.Errorf("expected result %v is the same as Exists", i)
+				}
+			}
+		})
+	}
+}
+
+func TestExistsMany_Empty(t *testing.T) {
+	if len(ExistsMany(Must(WithCapacity(1024, 3)), nil)) != 0 {
+		t.Errorf("expected empty result")
+	}
+}
@@ -1,8 +1,11 @@
 package bf
 
 import (
+	"context"
 	"fmt"
 	"github.com/toniphan21/go-bf/internal"
+	"math/rand"
+	"sync"
 	"testing"
 )
 
@@ -34,6 +37,246 @@ func BenchmarkBloomFilter_WithFNV_Exists(b *testing.B) {
 	}
 }
 
+func benchmarkFiltersForKeys() []BloomFilter {
+	filters := make([]BloomFilter, 30)
+	for i := range filters {
+		filters[i] = Must(WithAccuracy(0.01, 1_000_000))
+	}
+	return filters
+}
+
+func BenchmarkExists_ManyFilters(b *testing.B) {
+	filters := benchmarkFiltersForKeys()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		item := []byte(fmt.Sprintf("%d", i))
+		for _, f := range filters {
+			f.Exists(item)
+		}
+	}
+}
+
+func BenchmarkExistsKeys_ManyFilters(b *testing.B) {
+	filters := benchmarkFiltersForKeys()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h := filters[0].Keys([]byte(fmt.Sprintf("%d", i)))
+		for _, f := range filters {
+			_, _ = f.ExistsKeys(h)
+		}
+	}
+}
+
+func benchmarkFiltersForBitSlicedIndex() []BloomFilter {
+	filters := make([]BloomFilter, 10_000)
+	for i := range filters {
+		filters[i] = Must(WithAccuracy(0.01, 100))
+		for j := 0; j < 100; j++ {
+			filters[i].Add([]byte(fmt.Sprintf("%d", rand.Intn(1_000_000))))
+		}
+	}
+	return filters
+}
+
+func BenchmarkExistsKeys_10000Filters(b *testing.B) {
+	filters := benchmarkFiltersForBitSlicedIndex()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h := filters[0].Keys([]byte(fmt.Sprintf("%d", i)))
+		for _, f := range filters {
+			_, _ = f.ExistsKeys(h)
+		}
+	}
+}
+
+func BenchmarkBitSlicedIndex_Query_10000Filters(b *testing.B) {
+	x, _ := NewBitSlicedIndexFrom(benchmarkFiltersForBitSlicedIndex()...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Query([]byte(fmt.Sprintf("%d", i)))
+	}
+}
+
+func benchmarkItemsForBuilder() [][]byte {
+	items := make([][]byte, 1_000_000)
+	for i := range items {
+		items[i] = []byte(fmt.Sprintf("%d", i))
+	}
+	return items
+}
+
+func BenchmarkAdd_1MItems(b *testing.B) {
+	items := benchmarkItemsForBuilder()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f := Must(WithAccuracy(0.01, 1_000_000))
+		for _, item := range items {
+			f.Add(item)
+		}
+	}
+}
+
+func BenchmarkBuilder_FromSlice_1MItems(b *testing.B) {
+	items := benchmarkItemsForBuilder()
+	builder := Builder{Config: WithAccuracy(0.01, 1_000_000)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = builder.FromSlice(context.Background(), items)
+	}
+}
+
+func BenchmarkNew_ShortLived(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		f := Must(WithAccuracy(0.01, 10_000), WithFNV())
+		f.Add([]byte("anything"))
+	}
+}
+
+func BenchmarkTemplate_ShortLived(b *testing.B) {
+	tpl, _ := NewTemplate(WithAccuracy(0.01, 10_000), WithFNV())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f, _ := tpl.Get()
+		f.Add([]byte("anything"))
+		tpl.Put(f)
+	}
+}
+
+func BenchmarkSnapshot_HugeFilter(b *testing.B) {
+	skipHugeBench(b)
+	f := Must(WithCapacity(1<<30, 3))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Snapshot(f)
+		f.Add([]byte(fmt.Sprintf("%d", i)))
+	}
+}
+
+func BenchmarkTyped_Integer_Add(b *testing.B) {
+	typed, _ := NewTyped(Must(WithAccuracy(0.01, 1_000_000), WithFNV()), IntegerEncoder[int]())
+	for i := 0; i < b.N; i++ {
+		typed.Add(i)
+	}
+}
+
+func BenchmarkTyped_String_Exists(b *testing.B) {
+	typed, _ := NewTyped(Must(WithAccuracy(0.01, 1_000_000), WithFNV()), StringEncoder[string]())
+	for i := 0; i < b.N; i++ {
+		typed.Exists("anything")
+	}
+}
+
+func BenchmarkNew_HugeFilter_Heap(b *testing.B) {
+	skipHugeBench(b)
+	for i := 0; i < b.N; i++ {
+		Must(WithCapacity(1<<31, 3))
+	}
+}
+
+func BenchmarkNew_HugeFilter_Mmap(b *testing.B) {
+	skipHugeBench(b)
+	for i := 0; i < b.N; i++ {
+		f := Must(WithCapacity(1<<31, 3), WithStorage(MmapStorageFactory{}))
+		_ = f.Storage().(Releaser).Release()
+	}
+}
+
+// skipHugeBench skips benchmarks which allocate hundreds of MB per run with
+// -short.
+func skipHugeBench(b *testing.B) {
+	if testing.Short() {
+		b.Skip("skipping huge filter benchmark in short mode")
+	}
+}
+
+var hugeFilterOnce sync.Once
+var hugeFilter BloomFilter
+var hugeFilterItems [][]byte
+
+// hugeFilterForBench is the biggest filter, 512 MB, filled randomly so every
+// page is touched. It is built once for every run of the benchmarks and kept
+// until the process exits.
+func hugeFilterForBench(b *testing.B) (BloomFilter, [][]byte) {
+	skipHugeBench(b)
+	hugeFilterOnce.Do(func() {
+		hugeFilter = Must(WithCapacity(1<<32-1, 7), WithFNV(), WithStorage(MmapStorageFactory{HugePages: true}))
+		r := rand.New(rand.NewSource(1))
+		data := hugeFilter.Storage().(*bitset).data
+		for i := range data {
+			data[i] = r.Uint64() | r.Uint64()
+		}
+
+		hugeFilterItems = make([][]byte, 1<<16)
+		for i := range hugeFilterItems {
+			hugeFilterItems[i] = []byte(fmt.Sprintf("%d", i))
+		}
+	})
+	return hugeFilter, hugeFilterItems
+}
+
+func BenchmarkExists_HugeFilter(b *testing.B) {
+	f, items := hugeFilterForBench(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		f.Exists(items[i%len(items)])
+	}
+}
+
+func BenchmarkExistsMany_HugeFilter(b *testing.B) {
+	f, items := hugeFilterForBench(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i += 4096 {
+		lo := i % len(items)
+		ExistsMany(f, items[lo:lo+4096])
+	}
+}
+
+func wordsForBench() ([]uint64, []uint64) {
+	r := rand.New(rand.NewSource(1))
+	a, b := make([]uint64, 1<<16), make([]uint64, 1<<16)
+	for i := range a {
+		a[i], b[i] = r.Uint64(), r.Uint64()
+	}
+	return a, b
+}
+
+func benchmarkWords(b *testing.B, fn func(dst, src []uint64)) {
+	dst, src := wordsForBench()
+	b.SetBytes(int64(len(dst) * bitsetDataSize / 8))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		fn(dst, src)
+	}
+}
+
+func BenchmarkWords_Or(b *testing.B)             { benchmarkWords(b, orWords) }
+func BenchmarkWords_Or_Generic(b *testing.B)     { benchmarkWords(b, orWordsGeneric) }
+func BenchmarkWords_And(b *testing.B)            { benchmarkWords(b, andWords) }
+func BenchmarkWords_And_Generic(b *testing.B)    { benchmarkWords(b, andWordsGeneric) }
+func BenchmarkWords_AndNot(b *testing.B)         { benchmarkWords(b, andNotWords) }
+func BenchmarkWords_AndNot_Generic(b *testing.B) { benchmarkWords(b, andNotWordsGeneric) }
+func BenchmarkWords_Xor(b *testing.B)            { benchmarkWords(b, xorWords) }
+func BenchmarkWords_Xor_Generic(b *testing.B)    { benchmarkWords(b, xorWordsGeneric) }
+
+func BenchmarkWords_Popcount(b *testing.B) {
+	benchmarkWords(b, func(dst, _ []uint64) { popcountWords(dst) })
+}
+
+func BenchmarkWords_Popcount_Generic(b *testing.B) {
+	benchmarkWords(b, func(dst, _ []uint64) { popcountWordsGeneric(dst) })
+}
+
+func BenchmarkWords_OrCount(b *testing.B) {
+	benchmarkWords(b, func(dst, src []uint64) { orCountWords(dst, src) })
+}
+
+func BenchmarkWords_OrCount_Generic(b *testing.B) {
+	benchmarkWords(b, func(dst, src []uint64) { orCountWordsGeneric(dst, src) })
+}
+
 func initForBench(count, m, n int) []BloomFilter {
 	result := make([]BloomFilter, count)
 	for i := 0; i < count; i++ {
@@ -71,3 +314,21 @@ func BenchmarkBloomFilter_Clone(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkUnionAll(b *testing.B) {
+	bfs := initForBench(256, 1_000_000, 100)
+	b.Run("bench", func(pb *testing.B) {
+		for i := 0; i < pb.N; i++ {
+			_, _ = UnionAll(bfs...)
+		}
+	})
+}
+
+func BenchmarkIntersectAll(b *testing.B) {
+	bfs := initForBench(256, 1_000_000, 100)
+	b.Run("bench", func(pb *testing.B) {
+		for i := 0; i < pb.N; i++ {
+			_, _ = IntersectAll(bfs...)
+		}
+	})
+}
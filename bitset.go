@@ -1,16 +1,27 @@
 package bf
 
-import "math"
+import (
+	"encoding/binary"
+	"sync/atomic"
+)
 
-const bitsetDataSize = 32 << (^uint(0) >> 63) // 32 or 64
+// bitsetDataSize is the same on every platform so the layout of data does not
+// depend on the size of uint, bit i is bit i % 64 of the word i / 64.
+const bitsetDataSize = 64
 
 type bitset struct {
-	data     []uint
+	data     []uint64
 	capacity uint32
+	release  func() error
+
+	// cow holds words instead of data after a snapshot is taken, see
+	// storage_cow.go. A read-only bitset rejects every change.
+	cow      *cowPages
+	readOnly bool
 }
 
 func newBitset(n, capacity uint32) *bitset {
-	return &bitset{data: make([]uint, n), capacity: capacity}
+	return &bitset{data: make([]uint64, n), capacity: capacity}
 }
 
 func (b *bitset) Capacity() uint32 {
@@ -18,32 +29,66 @@ func (b *bitset) Capacity() uint32 {
 }
 
 func (b *bitset) Set(index uint32) {
+	b.checkWritable()
 	if index >= b.capacity {
 		return
 	}
 
 	n, m := b.indexing(index)
+	if b.cow != nil {
+		*b.cow.writable(n) |= m
+		return
+	}
 	d := b.data[n] | m
 	b.data[n] = d
 }
 
+// setAtomic is Set which is safe to call from many goroutines, the bitset must
+// not have snapshots.
+func (b *bitset) setAtomic(index uint32) {
+	if index >= b.capacity {
+		return
+	}
+
+	n, m := b.indexing(index)
+	addr := &b.data[n]
+	for {
+		d := atomic.LoadUint64(addr)
+		if d&m != 0 || atomic.CompareAndSwapUint64(addr, d, d|m) {
+			return
+		}
+	}
+}
+
 func (b *bitset) Clear(index uint32) {
+	b.checkWritable()
 	if index >= b.capacity {
 		return
 	}
 
 	n, m := b.indexing(index)
-	d := b.data[n] & (m ^ math.MaxUint)
+	if b.cow != nil {
+		*b.cow.writable(n) &^= m
+		return
+	}
+	d := b.data[n] &^ m
 	b.data[n] = d
 }
 
+// checkWritable panics with ErrReadOnlyStorage if the bitset is read-only.
+func (b *bitset) checkWritable() {
+	if b.readOnly {
+		panic(ErrReadOnlyStorage)
+	}
+}
+
 func (b *bitset) Get(index uint32) bool {
 	if index >= b.capacity {
 		return false
 	}
 
 	n, m := b.indexing(index)
-	d := b.data[n] & m
+	d := b.word(n) & m
 	return d > 0
 }
 
@@ -55,7 +100,7 @@ func (b *bitset) Equals(other Storage) bool {
 	return o.capacity == b.capacity
 }
 
-func (b *bitset) indexing(i uint32) (uint32, uint) {
+func (b *bitset) indexing(i uint32) (uint32, uint64) {
 	n := i / bitsetDataSize
 	m := i % bitsetDataSize
 
@@ -64,24 +109,121 @@ func (b *bitset) indexing(i uint32) (uint32, uint) {
 
 func (b *bitset) Intersect(other Storage) {
 	o, ok := other.(*bitset)
+	b.checkWritable()
 	if !ok {
 		return
 	}
 
-	l := len(b.data)
-	for i := 0; i < l; i++ {
-		b.data[i] &= o.data[i]
-	}
+	b.apply(o, andWords)
 }
 
 func (b *bitset) Union(other Storage) {
 	o, ok := other.(*bitset)
+	b.checkWritable()
+	if !ok {
+		return
+	}
+
+	b.apply(o, orWords)
+}
+
+func (b *bitset) Difference(other Storage) {
+	o, ok := other.(*bitset)
+	b.checkWritable()
+	if !ok {
+		return
+	}
+
+	b.apply(o, andNotWords)
+}
+
+func (b *bitset) Xor(other Storage) {
+	o, ok := other.(*bitset)
+	b.checkWritable()
 	if !ok {
 		return
 	}
 
-	l := len(b.data)
-	for i := 0; i < l; i++ {
-		b.data[i] |= o.data[i]
+	b.apply(o, xorWords)
+}
+
+func (b *bitset) Reset() {
+	b.checkWritable()
+	if b.cow != nil {
+		b.data = make([]uint64, b.size())
+		b.cow = nil
+		return
+	}
+
+	for i := range b.data {
+		b.data[i] = 0
+	}
+}
+
+func (b *bitset) Copy(other Storage) {
+	o, ok := other.(*bitset)
+	b.checkWritable()
+	if !ok || b.released() {
+		return
+	}
+
+	if b.cow != nil {
+		b.data = make([]uint64, b.size())
+		b.cow = nil
+	}
+	o.eachPage(func(offset int, words []uint64) {
+		copy(b.data[offset:], words)
+	})
+}
+
+// apply changes words of b by op with words of o, nothing is changed if b was
+// released.
+func (b *bitset) apply(o *bitset, op func(dst, src []uint64)) {
+	if b.released() {
+		return
+	}
+
+	b.flatten()
+	o.eachPage(func(offset int, words []uint64) {
+		op(b.data[offset:offset+len(words)], words)
+	})
+}
+
+func (b *bitset) count() uint32 {
+	var result int
+	b.eachPage(func(_ int, words []uint64) {
+		result += popcountWords(words)
+	})
+	return uint32(result)
+}
+
+// exportBytes writes words in little-endian byte order so bit i is bit i % 8 of
+// the byte i / 8, bytes beyond the capacity are dropped.
+func (b *bitset) exportBytes() []byte {
+	words := b.words()
+	result := make([]byte, len(words)*8)
+	for i, d := range words {
+		binary.LittleEndian.PutUint64(result[i*8:], d)
+	}
+	return result[:storageSizeInBytes(b.capacity)]
+}
+
+// importBytes reads data written by exportBytes, bits beyond the capacity are
+// cleared.
+func (b *bitset) importBytes(data []byte) {
+	b.checkWritable()
+	b.flatten()
+
+	var word [8]byte
+	for i := range b.data {
+		word = [8]byte{}
+		if i*8 < len(data) {
+			copy(word[:], data[i*8:])
+		}
+		b.data[i] = binary.LittleEndian.Uint64(word[:])
+	}
+
+	if m := b.capacity % bitsetDataSize; m > 0 && len(b.data) > 0 {
+		b.data[len(b.data)-1] &= 1<<m - 1
 	}
 }
@@ -116,7 +116,7 @@ func TestBitset_Equals_ReturnsTrueIfItIsABitsetAndHaveSameCapacity(t *testing.T)
 }
 
 func TestBitset_Intersect_DoesNothingIfStorageIsNotABitset(t *testing.T) {
-	b := &bitset{data: []uint{1, 2}}
+	b := &bitset{data: []uint64{1, 2}}
 	o := &mockStorage{getData: map[uint32]bool{}}
 	b.Intersect(o)
 	if b.data[0] != 1 || b.data[1] != 2 {
@@ -125,8 +125,8 @@ func TestBitset_Intersect_DoesNothingIfStorageIsNotABitset(t *testing.T) {
 }
 
 func TestBitset_Intersect(t *testing.T) {
-	a := &bitset{data: []uint{0, 2, 0b00110011}}
-	b := &bitset{data: []uint{1, 0, 0b01010101}}
+	a := &bitset{data: []uint64{0, 2, 0b00110011}}
+	b := &bitset{data: []uint64{1, 0, 0b01010101}}
 	a.Intersect(b)
 	if a.data[0] != 0 || a.data[1] != 0 || a.data[2] != 0b00010001 {
 		t.Errorf("Intersect should apply AND operator to all bytes")
@@ -137,7 +137,7 @@ func TestBitset_Intersect(t *testing.T) {
 }
 
 func TestBitset_Union_DoesNothingIfStorageIsNotABitset(t *testing.T) {
-	b := &bitset{data: []uint{1, 2}}
+	b := &bitset{data: []uint64{1, 2}}
 	o := &mockStorage{getData: map[uint32]bool{}}
 	b.Union(o)
 	if b.data[0] != 1 || b.data[1] != 2 {
@@ -146,8 +146,8 @@ func TestBitset_Union_DoesNothingIfStorageIsNotABitset(t *testing.T) {
 }
 
 func TestBitset_Union(t *testing.T) {
-	a := &bitset{data: []uint{0, 0, 2, 0b00110011}}
-	b := &bitset{data: []uint{0, 1, 0, 0b01010101}}
+	a := &bitset{data: []uint64{0, 0, 2, 0b00110011}}
+	b := &bitset{data: []uint64{0, 1, 0, 0b01010101}}
 	a.Union(b)
 	if a.data[0] != 0 || a.data[1] != 1 || a.data[2] != 2 && a.data[3] != 0b01110111 {
 		t.Errorf("Intersect should apply OR operator to all bytes")
@@ -157,6 +157,78 @@ func TestBitset_Union(t *testing.T) {
 	}
 }
 
+func TestBitset_Difference(t *testing.T) {
+	a := &bitset{data: []uint64{0b1111, 0b0110}}
+	b := &bitset{data: []uint64{0b0101, 0b0011}}
+	a.Difference(b)
+	if a.data[0] != 0b1010 || a.data[1] != 0b0100 {
+		t.Errorf("Difference should apply AND NOT operator to all words")
+	}
+	if b.data[0] != 0b0101 || b.data[1] != 0b0011 {
+		t.Errorf("Difference should not changed the given Storage data")
+	}
+
+	a.Difference(&mockStorage{})
+	if a.data[0] != 0b1010 || a.data[1] != 0b0100 {
+		t.Errorf("Expected do nothing something changed")
+	}
+}
+
+func TestBitset_Xor(t *testing.T) {
+	a := &bitset{data: []uint64{0b1111, 0b0110}}
+	b := &bitset{data: []uint64{0b0101, 0b0011}}
+	a.Xor(b)
+	if a.data[0] != 0b1010 || a.data[1] != 0b0101 {
+		t.Errorf("Xor should apply XOR operator to all words")
+	}
+
+	a.Xor(&mockStorage{})
+	if a.data[0] != 0b1010 || a.data[1] != 0b0101 {
+		t.Errorf("Expected do nothing something changed")
+	}
+}
+
+func TestBitset_Count(t *testing.T) {
+	a := &bitset{data: []uint64{0b1111, 0b0110, 0}}
+	if a.count() != 6 {
+		t.Errorf("Expected 6, got %v", a.count())
+	}
+	if orCountWords(a.data, []uint64{0b10000, 0b0001, 0b1}) != 9 {
+		t.Errorf("Expected 9, got %v", orCountWords(a.data, []uint64{0b10000, 0b0001, 0b1}))
+	}
+}
+
+func TestBitset_ExportAndImportBytes(t *testing.T) {
+	a := newBitset(2, bitsetDataSize+12)
+	indices := []uint32{0, 3, 8, 17, bitsetDataSize - 1, bitsetDataSize, bitsetDataSize + 11}
+	for _, i := range indices {
+		a.Set(i)
+	}
+
+	data := a.exportBytes()
+	if len(data) != bitsetDataSize/8+2 {
+		t.Errorf("Expected %d bytes, got %d", bitsetDataSize/8+2, len(data))
+	}
+	if data[0] != 0b00001001 || data[1] != 0b00000001 || data[2] != 0b00000010 {
+		t.Errorf("Expected bit i is stored in byte i/8, got %v", data)
+	}
+
+	b := newBitset(2, bitsetDataSize+12)
+	b.Set(1)
+	b.importBytes(data)
+	if !isArrayEquals(a.data, b.data) {
+		t.Errorf("Expected %v, got %v", a.data, b.data)
+	}
+}
+
+func TestBitset_ImportBytes_IgnoresBitsOutOfCapacity(t *testing.T) {
+	b := newBitset(1, 4)
+	b.importBytes([]byte{0xff})
+	if b.data[0] != 0b1111 {
+		t.Errorf("Expected %b, got %b", 0b1111, b.data[0])
+	}
+}
+
 func reverseByteBinaryString(b string) string {
 	n := len(b)
 	sb := strings.Builder{}
@@ -168,7 +240,7 @@ func reverseByteBinaryString(b string) string {
 	return sb.String()
 }
 
-func sprintfUintInBinary(b *[]uint) string {
+func sprintfUintInBinary(b *[]uint64) string {
 	result := make([]string, len(*b))
 	format := fmt.Sprintf("%%0%db", bitsetDataSize)
 	for i, bt := range *b {
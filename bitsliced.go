@@ -0,0 +1,256 @@
+package bf
+
+import (
+	"math/bits"
+	"sync"
+)
+
+/*
+BitSlicedIndex stores many compatible filters transposed: there is a row for
+every Storage index which holds the bit of every member filter at that index.
+An item is looked up by AND-ing the k rows of its indexes, which gives the ids
+of members where Exists is true without checking every filter.
+
+Members are copied when they are added, later changes of a member filter are
+not seen by the index. An id of a removed member is reused by the next Add.
+BitSlicedIndex is safe for concurrent use.
+*/
+type BitSlicedIndex struct {
+	mu       sync.RWMutex
+	hasher   Hasher
+	mapping  IndexMapping
+	capacity uint32
+	stride   int
+	rows     []uint64
+	used     []uint64
+	count    int
+}
+
+/*
+NewBitSlicedIndex creates an empty BitSlicedIndex for filters created by New
+with the same Config and options. Only the hasher and IndexMapping options are
+used, members could have any Storage.
+*/
+func NewBitSlicedIndex(config Config, opts ...OptionFunc) (*BitSlicedIndex, error) {
+	o, err := newOption(config, opts)
+	if err != nil {
+		return nil, err
+	}
+	if config.StorageCapacity() == 0 {
+		return nil, ErrInvalidStorageCapacity
+	}
+
+	h, err := o.hasher()
+	if err != nil {
+		return nil, err
+	}
+	return &BitSlicedIndex{hasher: h, mapping: o.mapping, capacity: config.StorageCapacity()}, nil
+}
+
+/*
+NewBitSlicedIndexFrom creates a BitSlicedIndex of the given filters, the id of
+a filter is its position. Every filter must be compatible with the first one,
+filters of other implementations whose IndexMapping is unknown are rejected.
+*/
+func NewBitSlicedIndexFrom(filters ...BloomFilter) (*BitSlicedIndex, error) {
+	if len(filters) == 0 {
+		return nil, ErrEmptyBloomFilters
+	}
+	for _, f := range filters {
+		if f == nil {
+			return nil, ErrNilBloomFilter
+		}
+	}
+
+	first := filters[0]
+	mapping, ok := indexMappingOf(first)
+	if !ok {
+		return nil, ErrUnknownIndexMapping
+	}
+	s, h := first.Storage(), first.Hasher()
+	if s == nil {
+		return nil, ErrStorageDifference
+	}
+	if h == nil {
+		return nil, ErrHasherDifference
+	}
+
+	x := &BitSlicedIndex{hasher: h, mapping: mapping, capacity: s.Capacity()}
+	x.grow(len(filters))
+	for _, f := range filters {
+		if _, err := x.Add(f); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// Add copies the filter into the index and returns its id. The filter must have
+// the same capacity, hasher and IndexMapping as the index, ErrUnknownIndexMapping
+// is returned for filters of other implementations.
+func (x *BitSlicedIndex) Add(f BloomFilter) (int, error) {
+	if f == nil {
+		return -1, ErrNilBloomFilter
+	}
+
+	// bits of a concurrent or durable filter are read from its snapshot
+	r := stable(f)
+	s := r.Storage()
+	if s == nil || s.Capacity() != x.capacity {
+		return -1, ErrStorageDifference
+	}
+	m, ok := indexMappingOf(r)
+	if !ok {
+		return -1, ErrUnknownIndexMapping
+	}
+	if m != x.mapping {
+		return -1, ErrHasherDifference
+	}
+	if !x.hasher.Equals(r.Hasher()) {
+		return -1, ErrHasherDifference
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	id := x.freeID()
+	x.used[id/64] |= 1 << (id % 64)
+	x.count++
+	x.setColumn(id, s)
+	return id, nil
+}
+
+// Remove removes the member of the given id, it reports whether the id was a
+// member.
+func (x *BitSlicedIndex) Remove(id int) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if id < 0 || id >= x.stride*64 || x.used[id/64]&(1<<(id%64)) == 0 {
+		return false
+	}
+
+	word, bit := id/64, uint64(1)<<(id%64)
+	for i := 0; i < int(x.capacity); i++ {
+		x.rows[i*x.stride+word] &^= bit
+	}
+	x.used[word] &^= bit
+	x.count--
+	return true
+}
+
+// Len returns the number of members.
+func (x *BitSlicedIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	return x.count
+}
+
+// Query returns ids of members which may contain the item in ascending order.
+func (x *BitSlicedIndex) Query(item []byte) []int {
+	return x.query(x.Keys(item).indexes)
+}
+
+// Keys hashes the item into a KeyHandle which could be used by QueryKeys and
+// compatible filters.
+func (x *BitSlicedIndex) Keys(item []byte) KeyHandle {
+	keys := x.hasher.Hash(item, 1)
+	indexes := make([]uint32, len(keys[0]))
+	for i, key := range keys[0] {
+		indexes[i] = x.mapping.index(key, x.capacity)
+	}
+	return KeyHandle{item: item, hasher: x.hasher, capacity: x.capacity, mapping: x.mapping, indexes: indexes}
+}
+
+// QueryKeys is Query by a KeyHandle without hashing, it returns an error if the
+// handle was hashed for an incompatible filter.
+func (x *BitSlicedIndex) QueryKeys(h KeyHandle) ([]int, error) {
+	if !h.Hashed() {
+		return x.Query(h.item), nil
+	}
+	if err := h.check(x.hasher, x.capacity, x.mapping); err != nil {
+		return nil, err
+	}
+	return x.query(h.indexes), nil
+}
+
+func (x *BitSlicedIndex) query(indexes []uint32) []int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	if x.count == 0 {
+		return nil
+	}
+
+	acc := make([]uint64, x.stride)
+	copy(acc, x.used)
+	for _, index := range indexes {
+		start := int(index) * x.stride
+		andWords(acc, x.rows[start:start+x.stride])
+	}
+
+	var result []int
+	for n, word := range acc {
+		for word != 0 {
+			result = append(result, n*64+bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+	return result
+}
+
+// freeID returns the lowest id which is not used, the rows grow if every id is
+// used.
+func (x *BitSlicedIndex) freeID() int {
+	for n, word := range x.used {
+		if word != ^uint64(0) {
+			return n*64 + bits.TrailingZeros64(^word)
+		}
+	}
+
+	id := x.stride * 64
+	if id == 0 {
+		x.grow(64)
+	} else {
+		x.grow(2 * id)
+	}
+	return id
+}
+
+// grow makes room for at least n members.
+func (x *BitSlicedIndex) grow(n int) {
+	stride := (n + 63) / 64
+	if stride <= x.stride {
+		return
+	}
+
+	rows := make([]uint64, int(x.capacity)*stride)
+	for i := 0; i < int(x.capacity); i++ {
+		copy(rows[i*stride:], x.rows[i*x.stride:(i+1)*x.stride])
+	}
+	used := make([]uint64, stride)
+	copy(used, x.used)
+
+	x.rows, x.used, x.stride = rows, used, stride
+}
+
+func (x *BitSlicedIndex) setColumn(id int, s Storage) {
+	word, bit := id/64, uint64(1)<<(id%64)
+	if b, ok := s.(*bitset); ok {
+		for n, w := range b.words() {
+			for w != 0 {
+				i := n*bitsetDataSize + bits.TrailingZeros64(w)
+				x.rows[i*x.stride+word] |= bit
+				w &= w - 1
+			}
+		}
+		return
+	}
+
+	for i := uint32(0); i < x.capacity; i++ {
+		if s.Get(i) {
+			x.rows[int(i)*x.stride+word] |= bit
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package bf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func makeFiltersForBitSlicedIndexTest(n int, opts ...OptionFunc) []BloomFilter {
+	filters := make([]BloomFilter, n)
+	for i := range filters {
+		filters[i] = Must(WithCapacity(1000, 3), opts...)
+		for j := 0; j < 20; j++ {
+			filters[i].Add([]byte(fmt.Sprintf("item-%d", i*7+j)))
+		}
+	}
+	return filters
+}
+
+func assertQueryMatchesExists(t *testing.T, x *BitSlicedIndex, filters map[int]BloomFilter) {
+	for i := 0; i < 1000; i++ {
+		item := []byte(fmt.Sprintf("item-%d", i))
+		var expected []int
+		for id := 0; id < 1000; id++ {
+			if f, ok := filters[id]; ok && f.Exists(item) {
+				expected = append(expected, id)
+			}
+		}
+
+		result := x.Query(item)
+		if !isArrayEquals(result, expected) {
+			t.Fatalf("%s: expected %v, got %v", item, expected, result)
+		}
+	}
+}
+
+func TestNewBitSlicedIndexFrom(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []OptionFunc
+	}{
+		{name: "bitset"},
+		{name: "fnv modulo", opts: []OptionFunc{WithFNV(), WithIndexMapping(IndexMappingModulo)}},
+		{name: "concurrent", opts: []OptionFunc{WithConcurrency()}},
+		{name: "custom storage", opts: []OptionFunc{WithStorage(sliceStorageFactory{})}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			filters := makeFiltersForBitSlicedIndexTest(150, tc.opts...)
+			x, err := NewBitSlicedIndexFrom(filters...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if x.Len() != 150 {
+				t.Errorf("expected 150 members, got %v", x.Len())
+			}
+
+			members := make(map[int]BloomFilter)
+			for i, f := range filters {
+				members[i] = f
+			}
+			assertQueryMatchesExists(t, x, members)
+		})
+	}
+}
+
+func TestBitSlicedIndex_AddAndRemove(t *testing.T) {
+	x, err := NewBitSlicedIndex(WithCapacity(1000, 3))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if x.Query([]byte("item-0")) != nil {
+		t.Errorf("expected no member")
+	}
+
+	filters := makeFiltersForBitSlicedIndexTest(130)
+	members := make(map[int]BloomFilter)
+	for i, f := range filters {
+		id, err := x.Add(f)
+		if err != nil || id != i {
+			t.Fatalf("expected id %v, got %v, %v", i, id, err)
+		}
+		members[id] = f
+	}
+
+	for _, id := range []int{0, 63, 64, 100} {
+		if !x.Remove(id) {
+			t.Errorf("expected %v is removed", id)
+		}
+		delete(members, id)
+	}
+	if x.Remove(63) || x.Remove(-1) || x.Remove(1000) {
+		t.Errorf("expected false for ids which are not members")
+	}
+	if x.Len() != 126 {
+		t.Errorf("expected 126 members, got %v", x.Len())
+	}
+	assertQueryMatchesExists(t, x, members)
+
+	// ids of removed members are reused from the lowest
+	for _, expected := range []int{0, 63, 64, 100, 130} {
+		id, _ := x.Add(filters[expected%130])
+		if id != expected {
+			t.Errorf("expected id %v, got %v", expected, id)
+		}
+		members[id] = filters[expected%130]
+	}
+	assertQueryMatchesExists(t, x, members)
+}
+
+func TestBitSlicedIndex_AddDurableFilterWhileItIsWritten(t *testing.T) {
+	x, _ := NewBitSlicedIndex(WithCapacity(4096, 3))
+	d := openDurableForTest(t, t.TempDir())
+	defer d.Close()
+	d.Add([]byte("d"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			d.Add([]byte(fmt.Sprintf("item-%d", i)))
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if _, err := x.Add(d); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	}
+	<-done
+
+	if len(x.Query([]byte("d"))) != 20 {
+		t.Errorf("expected every member contains d, got %v", x.Query([]byte("d")))
+	}
+}
+
+func TestBitSlicedIndex_Keys(t *testing.T) {
+	filters := makeFiltersForBitSlicedIndexTest(3)
+	x, _ := NewBitSlicedIndexFrom(filters...)
+
+	h := filters[1].Keys([]byte("item-7"))
+	ids, err := x.QueryKeys(h)
+	if err != nil || len(ids) == 0 || ids[0] != 0 || ids[len(ids)-1] != 1 {
+		t.Errorf("expected 0 and 1, got %v, %v", ids, err)
+	}
+
+	ok, err := filters[2].ExistsKeys(x.Keys([]byte("item-20")))
+	if !ok || err != nil {
+		t.Errorf("expected true, got %v, %v", ok, err)
+	}
+
+	if ids, _ := x.QueryKeys(NewKeyHandle([]byte("item-0"))); len(ids) != 1 || ids[0] != 0 {
+		t.Errorf("expected 0, got %v", ids)
+	}
+	if _, err := x.QueryKeys(Must(WithCapacity(2000, 3)).Keys([]byte("a"))); !errors.Is(err, ErrStorageDifference) {
+		t.Errorf("expected ErrStorageDifference, got %v", err)
+	}
+}
+
+func TestBitSlicedIndex_ReturnsErrIfFilterIsNotCompatible(t *testing.T) {
+	if _, err := NewBitSlicedIndexFrom(); !errors.Is(err, ErrEmptyBloomFilters) {
+		t.Errorf("expected ErrEmptyBloomFilters, got %v", err)
+	}
+	if _, err := NewBitSlicedIndexFrom(Must(WithCapacity(1000, 3)), nil); !errors.Is(err, ErrNilBloomFilter) {
+		t.Errorf("expected ErrNilBloomFilter, got %v", err)
+	}
+	if _, err := NewBitSlicedIndexFrom(wrappedBloomFilter{Must(WithCapacity(1000, 3))}); !errors.Is(err, ErrUnknownIndexMapping) {
+		t.Errorf("expected ErrUnknownIndexMapping, got %v", err)
+	}
+	if _, err := NewBitSlicedIndex(nil); !errors.Is(err, ErrNilConfig) {
+		t.Errorf("expected ErrNilConfig, got %v", err)
+	}
+
+	x, _ := NewBitSlicedIndex(WithCapacity(1000, 3))
+	cases := []struct {
+		filter   BloomFilter
+		expected error
+	}{
+		{filter: nil, expected: ErrNilBloomFilter},
+		{filter: Must(WithCapacity(1001, 3)), expected: ErrStorageDifference},
+		{filter: Must(WithCapacity(1000, 4)), expected: ErrHasherDifference},
+		{filter: Must(WithCapacity(1000, 3), WithFNV()), expected: ErrHasherDifference},
+		{filter: Must(WithCapacity(1000, 3), WithIndexMapping(IndexMappingModulo)), expected: ErrHasherDifference},
+		{filter: nilStorageFilter{Must(WithCapacity(1000, 3))}, expected: ErrStorageDifference},
+		{filter: wrappedBloomFilter{Must(WithCapacity(1000, 3))}, expected: ErrUnknownIndexMapping},
+	}
+	for _, tc := range cases {
+		if _, err := x.Add(tc.filter); !errors.Is(err, tc.expected) {
+			t.Errorf("expected %v, got %v", tc.expected, err)
+		}
+	}
+	if x.Len() != 0 {
+		t.Errorf("expected no member")
+	}
+}
@@ -1,54 +1,162 @@
 package bf
 
-type BloomFilter interface {
-	Add(item []byte)
+import "math"
 
+/*
+ReadOnlyFilter is the part of a filter which only queries it, it could be given
+to code which must not change the filter. Snapshot and Freeze return a
+ReadOnlyFilter which is safe for concurrent use. Every BloomFilter is a
+ReadOnlyFilter, a ReadOnlyFilter could be merged into a BloomFilter by Union,
+Intersect or CopyFrom and encoded by Marshal.
+*/
+type ReadOnlyFilter interface {
 	Exists(item []byte) bool
 
+	Keys(item []byte) KeyHandle
+
+	ExistsKeys(keys KeyHandle) (bool, error)
+
 	Count() int
 
+	AddCount() int
+
+	DistinctCount() int
+
 	Storage() Storage
 
 	Hasher() Hasher
 
-	Intersect(other BloomFilter) error
+	Config() Config
+}
+
+type BloomFilter interface {
+	ReadOnlyFilter
+
+	Add(item []byte)
+
+	TestAndAdd(item []byte) (existed bool)
+
+	AddKeys(keys KeyHandle) error
+
+	Intersect(other ReadOnlyFilter) error
 
-	Union(other BloomFilter) error
+	Union(other ReadOnlyFilter) error
 
 	Clone() (BloomFilter, error)
+
+	Reset()
+
+	CopyFrom(other ReadOnlyFilter) error
 }
 
 type bloomFilter struct {
-	option  Option
-	hasher  Hasher
-	storage Storage
-	count   int
+	option   Option
+	hasher   Hasher
+	storage  Storage
+	count    int
+	distinct int
 }
 
 func (b *bloomFilter) Add(item []byte) {
+	if b.option.distinctCount {
+		b.TestAndAdd(item)
+		return
+	}
+
 	keys := b.hasher.Hash(item, 1)
 	for _, key := range keys[0] {
-		index := uint32(key) % b.storage.Capacity()
-		b.storage.Set(index)
+		b.storage.Set(b.index(key))
 	}
-	b.count++
+	b.inserted(true)
 }
 
 func (b *bloomFilter) Exists(item []byte) bool {
 	keys := b.hasher.Hash(item, 1)
 	for _, key := range keys[0] {
-		index := uint32(key) % b.storage.Capacity()
-		if !b.storage.Get(index) {
+		if !b.storage.Get(b.index(key)) {
 			return false
 		}
 	}
 	return true
 }
 
+// TestAndAdd hashes the item once, reports whether it existed then adds it.
+func (b *bloomFilter) TestAndAdd(item []byte) bool {
+	existed := true
+	keys := b.hasher.Hash(item, 1)
+	for _, key := range keys[0] {
+		index := b.index(key)
+		if !b.storage.Get(index) {
+			existed = false
+			b.storage.Set(index)
+		}
+	}
+	b.inserted(existed)
+	return existed
+}
+
+// inserted updates counters after an item is added, existed is false if the
+// item flipped at least one bit.
+func (b *bloomFilter) inserted(existed bool) {
+	if b.count >= 0 {
+		b.count++
+	}
+	if !existed {
+		b.distinct++
+	}
+}
+
+// merged updates counters after the Storage is changed by other filters, the
+// number of distinct items is estimated from the number of set bits.
+func (b *bloomFilter) merged() {
+	b.count = -1
+	if b.option.distinctCount {
+		b.distinct = b.estimateDistinct()
+	}
+}
+
+func (b *bloomFilter) estimateDistinct() int {
+	m := b.storage.Capacity()
+	k := b.option.config.NumberOfHashFunctions()
+	return int(math.Round(estimateCount(countBits(b.storage), m, k)))
+}
+
+func (b *bloomFilter) index(key Key) uint32 {
+	return b.option.mapping.index(key, b.storage.Capacity())
+}
+
+func (b *bloomFilter) indexes(item []byte) []uint32 {
+	keys := b.hasher.Hash(item, 1)
+	result := make([]uint32, len(keys[0]))
+	for i, key := range keys[0] {
+		result[i] = b.index(key)
+	}
+	return result
+}
+
+// Count returns DistinctCount WithDistinctCount, otherwise AddCount.
 func (b *bloomFilter) Count() int {
+	if b.option.distinctCount {
+		return b.distinct
+	}
 	return b.count
 }
 
+// AddCount returns the number of Add calls, -1 if it is unknown after filters
+// are merged.
+func (b *bloomFilter) AddCount() int {
+	return b.count
+}
+
+// DistinctCount returns the number of added items which flipped at least one
+// bit WithDistinctCount, otherwise -1. It is estimated after filters are merged.
+func (b *bloomFilter) DistinctCount() int {
+	if b.option.distinctCount {
+		return b.distinct
+	}
+	return -1
+}
+
 func (b *bloomFilter) Storage() Storage {
 	return b.storage
 }
@@ -57,29 +165,19 @@ func (b *bloomFilter) Hasher() Hasher {
 	return b.hasher
 }
 
-func (b *bloomFilter) assertOtherBloomFilterIsTheSame(other BloomFilter) error {
-	if other == nil {
-		return ErrNilBloomFilter
-	}
-
-	if !b.storage.Equals(other.Storage()) {
-		return ErrStorageDifference
-	}
-
-	if !b.hasher.Equals(other.Hasher()) {
-		return ErrHasherDifference
-	}
-	return nil
+func (b *bloomFilter) Config() Config {
+	return b.option.config
 }
 
-func (b *bloomFilter) Intersect(other BloomFilter) error {
-	if err := b.assertOtherBloomFilterIsTheSame(other); err != nil {
+func (b *bloomFilter) Intersect(other ReadOnlyFilter) error {
+	other = stable(other)
+	if err := Compatible(b, other); err != nil {
 		return err
 	}
 
 	if bi, ok := b.storage.(BatchIntersect); ok {
 		bi.Intersect(other.Storage())
-		b.count = -1
+		b.merged()
 		return nil
 	}
 
@@ -89,18 +187,19 @@ func (b *bloomFilter) Intersect(other BloomFilter) error {
 			b.storage.Clear(i)
 		}
 	}
-	b.count = -1
+	b.merged()
 	return nil
 }
 
-func (b *bloomFilter) Union(other BloomFilter) error {
-	if err := b.assertOtherBloomFilterIsTheSame(other); err != nil {
+func (b *bloomFilter) Union(other ReadOnlyFilter) error {
+	other = stable(other)
+	if err := Compatible(b, other); err != nil {
 		return err
 	}
 
 	if bi, ok := b.storage.(BatchUnion); ok {
 		bi.Union(other.Storage())
-		b.count = -1
+		b.merged()
 		return nil
 	}
 
@@ -110,7 +209,7 @@ func (b *bloomFilter) Union(other BloomFilter) error {
 			b.storage.Set(i)
 		}
 	}
-	b.count = -1
+	b.merged()
 	return nil
 }
 
@@ -120,10 +219,79 @@ func (b *bloomFilter) Clone() (BloomFilter, error) {
 		return nil, err
 	}
 
-	err = r.Union(b)
-	if err != nil {
-		return nil, err
-	}
+	copyStorage(r.storage, b.storage)
 	r.count = b.count
+	r.distinct = b.distinct
 	return r, nil
 }
+
+// Reset clears every bit and counter, the filter is the same as a new one.
+func (b *bloomFilter) Reset() {
+	if br, ok := b.storage.(BatchReset); ok {
+		br.Reset()
+	} else {
+		for i := uint32(0); i < b.storage.Capacity(); i++ {
+			b.storage.Clear(i)
+		}
+	}
+	b.count = 0
+	b.distinct = 0
+}
+
+// CopyFrom replaces bits and counters of the filter by the ones of a compatible
+// filter, the number of distinct items is estimated if other does not count it.
+func (b *bloomFilter) CopyFrom(other ReadOnlyFilter) error {
+	if err := Compatible(b, other); err != nil {
+		return err
+	}
+	if other == ReadOnlyFilter(b) {
+		return nil
+	}
+
+	other = stable(other)
+	copyStorage(b.storage, other.Storage())
+	if o, ok := bloomFilterOf(other); ok {
+		b.count = o.count
+		b.distinct = o.distinct
+		return nil
+	}
+
+	b.count = other.AddCount()
+	b.distinct = other.DistinctCount()
+	if b.distinct < 0 {
+		b.distinct = 0
+		if b.option.distinctCount {
+			b.distinct = b.estimateDistinct()
+		}
+	}
+	return nil
+}
+
+// bloomFilterOf returns the bloomFilter of built-in filters which are held in
+// memory.
+func bloomFilterOf(f ReadOnlyFilter) (*bloomFilter, bool) {
+	switch v := f.(type) {
+	case *bloomFilter:
+		return v, true
+	case *concurrentBloomFilter:
+		return v.filter, true
+	case *readOnlyFilter:
+		return v.filter, true
+	}
+	return nil, false
+}
+
+func copyStorage(dst, src Storage) {
+	if bc, ok := dst.(BatchCopy); ok {
+		bc.Copy(src)
+		return
+	}
+
+	for i := uint32(0); i < src.Capacity(); i++ {
+		if src.Get(i) {
+			dst.Set(i)
+		} else {
+			dst.Clear(i)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package bf
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"testing"
 )
 
@@ -100,7 +102,7 @@ func isKeysEquals(a, b [][]Key) bool {
 	return true
 }
 
-func isArrayEquals[T byte | Key | uint](a, b []T) bool {
+func isArrayEquals[T byte | Key | uint64 | int](a, b []T) bool {
 	if a == nil && b == nil {
 		return true
 	}
@@ -133,7 +135,7 @@ func (m *mockHasher) assertHashCalledWith(t *testing.T, input []byte) {
 func TestBloomFilter_Add(t *testing.T) {
 	hash := &mockHasher{hash: [][]Key{{11, 3, 55, 77}}}
 	storage := &mockStorage{capacity: 10}
-	f := bloomFilter{hasher: hash, storage: storage}
+	f := bloomFilter{option: Option{mapping: IndexMappingModulo}, hasher: hash, storage: storage}
 	f.Add([]byte("input"))
 
 	hash.assertHashCalledWith(t, []byte("input"))
@@ -143,10 +145,19 @@ func TestBloomFilter_Add(t *testing.T) {
 	}
 }
 
+func TestBloomFilter_Add_MultiplyShift(t *testing.T) {
+	hash := &mockHasher{hash: [][]Key{{0, 1 << 31, 1<<32 - 1, 858993460}}}
+	storage := &mockStorage{capacity: 10}
+	f := bloomFilter{hasher: hash, storage: storage}
+	f.Add([]byte("input"))
+
+	storage.assertSetCalledWith(t, []uint32{0, 5, 9, 2})
+}
+
 func TestBloomFilter_Count(t *testing.T) {
 	hash := &mockHasher{hash: [][]Key{{11, 3, 55, 77}}}
 	storage := &mockStorage{capacity: 10}
-	f := bloomFilter{hasher: hash, storage: storage}
+	f := bloomFilter{option: Option{mapping: IndexMappingModulo}, hasher: hash, storage: storage}
 	if f.Count() != 0 {
 		t.Errorf("expected count is 0")
 	}
@@ -193,7 +204,7 @@ func TestBloomFilter_Exists(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			hash := &mockHasher{hash: tc.hash}
 			storage := &mockStorage{getData: tc.data, capacity: 10}
-			f := bloomFilter{hasher: hash, storage: storage}
+			f := bloomFilter{option: Option{mapping: IndexMappingModulo}, hasher: hash, storage: storage}
 
 			result := f.Exists([]byte("input"))
 
@@ -205,6 +216,104 @@ func TestBloomFilter_Exists(t *testing.T) {
 	}
 }
 
+func TestBloomFilter_TestAndAdd(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     map[uint32]bool
+		expected bool
+		set      []uint32
+	}{
+		{
+			name:     "no cell set",
+			data:     map[uint32]bool{0: false, 1: false, 2: false},
+			expected: false,
+			set:      []uint32{1, 2},
+		},
+		{
+			name:     "1 cell set",
+			data:     map[uint32]bool{0: false, 1: true, 2: false},
+			expected: false,
+			set:      []uint32{2},
+		},
+		{
+			name:     "2 cells set",
+			data:     map[uint32]bool{0: false, 1: true, 2: true},
+			expected: true,
+			set:      []uint32{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hash := &mockHasher{hash: [][]Key{{1, 2}}}
+			storage := &mockStorage{getData: tc.data, capacity: 10}
+			f := bloomFilter{option: Option{mapping: IndexMappingModulo}, hasher: hash, storage: storage}
+
+			result := f.TestAndAdd([]byte("input"))
+
+			hash.assertHashCalledWith(t, []byte("input"))
+			storage.assertSetCalledWith(t, tc.set)
+			if result != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, result)
+			}
+			if f.count != 1 {
+				t.Errorf("expected count is increased to 1")
+			}
+		})
+	}
+}
+
+func TestBloomFilter_DistinctCount(t *testing.T) {
+	f := Must(WithCapacity(4096, 3))
+	f.Add([]byte("a"))
+	f.Add([]byte("a"))
+	if f.Count() != 2 || f.AddCount() != 2 || f.DistinctCount() != -1 {
+		t.Errorf("expected counters 2, 2, -1, got %v, %v, %v", f.Count(), f.AddCount(), f.DistinctCount())
+	}
+
+	d := Must(WithCapacity(4096, 3), WithDistinctCount())
+	d.Add([]byte("a"))
+	d.Add([]byte("a"))
+	d.TestAndAdd([]byte("b"))
+	if d.Count() != 2 || d.AddCount() != 3 || d.DistinctCount() != 2 {
+		t.Errorf("expected counters 2, 3, 2, got %v, %v, %v", d.Count(), d.AddCount(), d.DistinctCount())
+	}
+
+	c, _ := d.Clone()
+	if c.AddCount() != 3 || c.DistinctCount() != 2 {
+		t.Errorf("expected Clone keeps counters, got %v, %v", c.AddCount(), c.DistinctCount())
+	}
+}
+
+func TestBloomFilter_DistinctCountIsEstimatedAfterUnion(t *testing.T) {
+	a := Must(WithAccuracy(0.01, 10_000), WithDistinctCount())
+	b := Must(WithAccuracy(0.01, 10_000), WithDistinctCount(), WithConcurrency())
+	for i := 0; i < 3000; i++ {
+		a.Add([]byte(fmt.Sprintf("%d", i)))
+		b.Add([]byte(fmt.Sprintf("%d", i+1000)))
+	}
+
+	if err := a.Union(b); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if a.AddCount() != -1 {
+		t.Errorf("expected add count is unknown, got %v", a.AddCount())
+	}
+	if math.Abs(float64(a.Count()-4000)) > 100 {
+		t.Errorf("expected distinct count about 4000, got %v", a.Count())
+	}
+
+	a.Add([]byte("new"))
+	if a.AddCount() != -1 {
+		t.Errorf("expected add count is still unknown, got %v", a.AddCount())
+	}
+
+	u, _ := UnionAll(b, b)
+	if math.Abs(float64(u.Count()-3000)) > 100 {
+		t.Errorf("expected distinct count about 3000, got %v", u.Count())
+	}
+}
+
 func TestBloomFilter_Storage(t *testing.T) {
 	storage := &mockStorage{}
 	f := bloomFilter{storage: storage}
@@ -225,6 +334,16 @@ func TestBloomFilter_Hasher(t *testing.T) {
 	}
 }
 
+func TestBloomFilter_Config(t *testing.T) {
+	cf := &dummyConfig{k: 3, capacity: 100}
+	f := bloomFilter{option: Option{config: cf}}
+
+	result := f.Config()
+	if result != cf {
+		t.Errorf("expected %v, got %v", cf, result)
+	}
+}
+
 func TestIntersect_ReturnsErrIfGivenBloomFilterIsNil(t *testing.T) {
 	a := bloomFilter{storage: &mockStorage{capacity: 1}}
 	err := a.Intersect(nil)
@@ -288,8 +407,8 @@ func TestIntersect_UseClearToChangeDataOfCurrentInstance(t *testing.T) {
 }
 
 func TestIntersect_ShouldUseIntersectIfTheStorageIsBatchIntersect(t *testing.T) {
-	as := &bitset{data: []uint{0, 2, 0b00110011}}
-	bs := &bitset{data: []uint{1, 0, 0b01010101}}
+	as := &bitset{data: []uint64{0, 2, 0b00110011}}
+	bs := &bitset{data: []uint64{1, 0, 0b01010101}}
 
 	a := bloomFilter{storage: as, hasher: &mockHasher{hash: [][]Key{{1, 2}}}}
 	b := bloomFilter{storage: bs, hasher: &mockHasher{hash: [][]Key{{1, 2}}}}
@@ -373,8 +492,8 @@ func TestUnion_UseSetToChangeDataOfCurrentInstance(t *testing.T) {
 }
 
 func TestUnion_ShouldUseIntersectIfTheStorageIsBatchIntersect(t *testing.T) {
-	as := &bitset{data: []uint{0, 0, 2, 0b00110011}}
-	bs := &bitset{data: []uint{0, 1, 0, 0b01010101}}
+	as := &bitset{data: []uint64{0, 0, 2, 0b00110011}}
+	bs := &bitset{data: []uint64{0, 1, 0, 0b01010101}}
 
 	a := bloomFilter{storage: as, hasher: &mockHasher{hash: [][]Key{{1, 2}}}}
 	b := bloomFilter{storage: bs, hasher: &mockHasher{hash: [][]Key{{1, 2}}}}
@@ -398,7 +517,7 @@ func TestUnion_ShouldUseIntersectIfTheStorageIsBatchIntersect(t *testing.T) {
 func TestClone_ReturnErrIfStorageFactoryReturnError(t *testing.T) {
 	expected := errors.New("whatever")
 	h := &mockHasher{hash: [][]Key{{1, 2}}}
-	storage := &bitset{data: []uint{0, 0, 2, 0b00110011}}
+	storage := &bitset{data: []uint64{0, 0, 2, 0b00110011}}
 
 	a := bloomFilter{
 		option: Option{
@@ -421,33 +540,33 @@ func TestClone_ReturnErrIfStorageFactoryReturnError(t *testing.T) {
 	}
 }
 
-func TestClone_UseUnionToCopyData_IfUnionReturnErrItShouldForwardTheError(t *testing.T) {
+func TestClone_UseGetSetAndClearIfStorageIsNotBatchCopy(t *testing.T) {
 	h := &mockHasher{hash: [][]Key{{1, 2}}}
-	storage := &bitset{data: []uint{0, 0, 2, 0b00110011}}
+	source := &mockStorage{getData: map[uint32]bool{0: false, 1: true, 2: true, 3: false}, capacity: 4}
+	target := &mockStorage{capacity: 4}
 
 	a := bloomFilter{
 		option: Option{
 			config:         &dummyConfig{},
-			storageFactory: &stubStorageFactory{storage: storage},
+			storageFactory: &stubStorageFactory{storage: target},
 			hasherFactory:  &stubHasherFactory{hasher: h},
 		},
 		count:   123,
-		storage: storage,
-		hasher:  &mockHasher{hash: [][]Key{{2, 1}}},
+		storage: source,
+		hasher:  h,
 	}
-	r, err := a.Clone()
+	_, err := a.Clone()
 
-	if r != nil {
-		t.Errorf("expected nil, got %v", r)
-	}
-	if err == nil || !errors.Is(err, ErrHasherDifference) {
-		t.Errorf("expected %v, got %v", ErrHasherDifference, err)
+	if err != nil {
+		t.Errorf("expected nil, got %v", err)
 	}
+	target.assertSetCalledWith(t, []uint32{1, 2})
+	target.assertClearCalledWith(t, []uint32{0, 3})
 }
 
 func TestClone_ShouldReturnNewInstanceWithTheSameData(t *testing.T) {
 	h := &mockHasher{hash: [][]Key{{1, 2}}}
-	storage := &bitset{data: []uint{0, 0, 2, 0b00110011}}
+	storage := &bitset{data: []uint64{0, 0, 2, 0b00110011}}
 
 	a := bloomFilter{
 		option: Option{
@@ -499,3 +618,114 @@ func TestClone_ShouldReturnNewInstanceWithTheSameData(t *testing.T) {
 		t.Errorf("expected 123, got %v", r.Count())
 	}
 }
+
+func TestReset(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []OptionFunc
+	}{
+		{name: "bitset"},
+		{name: "distinct count", opts: []OptionFunc{WithDistinctCount()}},
+		{name: "concurrent", opts: []OptionFunc{WithConcurrency()}},
+		{name: "custom storage", opts: []OptionFunc{WithStorage(sliceStorageFactory{})}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := Must(WithCapacity(1000, 3), tc.opts...)
+			for i := 0; i < 100; i++ {
+				f.Add([]byte(fmt.Sprintf("item-%d", i)))
+			}
+			_ = f.Union(Must(WithCapacity(1000, 3), tc.opts...))
+
+			f.Reset()
+
+			if f.AddCount() != 0 || f.Count() != 0 {
+				t.Errorf("expected counts are 0, got %v, %v", f.AddCount(), f.Count())
+			}
+			for i := uint32(0); i < 1000; i++ {
+				if f.Storage().Get(i) {
+					t.Fatalf("expected bit %v is cleared", i)
+				}
+			}
+
+			f.Add([]byte("item"))
+			if !f.Exists([]byte("item")) || f.Count() != 1 {
+				t.Errorf("expected the filter is usable after Reset")
+			}
+		})
+	}
+}
+
+func TestCopyFrom(t *testing.T) {
+	cases := []struct {
+		name  string
+		opts  []OptionFunc
+		other func(opts []OptionFunc) BloomFilter
+	}{
+		{name: "bitset"},
+		{name: "distinct count", opts: []OptionFunc{WithDistinctCount()}},
+		{name: "concurrent", opts: []OptionFunc{WithConcurrency()}},
+		{name: "custom storage", opts: []OptionFunc{WithStorage(sliceStorageFactory{})}},
+		{name: "other implementation", other: func(opts []OptionFunc) BloomFilter {
+			return wrappedBloomFilter{Must(WithCapacity(1000, 3), opts...)}
+		}},
+		{name: "other implementation distinct count", opts: []OptionFunc{WithDistinctCount()}, other: func(opts []OptionFunc) BloomFilter {
+			return wrappedBloomFilter{Must(WithCapacity(1000, 3))}
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			other := Must(WithCapacity(1000, 3), tc.opts...)
+			if tc.other != nil {
+				other = tc.other(tc.opts)
+			}
+			for i := 0; i < 50; i++ {
+				other.Add([]byte(fmt.Sprintf("other-%d", i)))
+			}
+			f := Must(WithCapacity(1000, 3), tc.opts...)
+			for i := 0; i < 100; i++ {
+				f.Add([]byte(fmt.Sprintf("item-%d", i)))
+			}
+
+			if err := f.CopyFrom(other); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			for i := uint32(0); i < 1000; i++ {
+				if f.Storage().Get(i) != other.Storage().Get(i) {
+					t.Fatalf("unexpected bit %v", i)
+				}
+			}
+			if f.AddCount() != 50 || math.Abs(float64(f.Count()-50)) > 3 {
+				t.Errorf("expected counts about 50, got %v, %v", f.AddCount(), f.Count())
+			}
+			other.Add([]byte("later"))
+			if f.AddCount() != 50 {
+				t.Errorf("expected the filter is not changed by other")
+			}
+		})
+	}
+}
+
+func TestCopyFrom_ReturnsErrIfFiltersAreNotCompatible(t *testing.T) {
+	f := Must(WithCapacity(1000, 3))
+	f.Add([]byte("item"))
+
+	if err := f.CopyFrom(nil); !errors.Is(err, ErrNilBloomFilter) {
+		t.Errorf("expected %v, got %v", ErrNilBloomFilter, err)
+	}
+	if err := f.CopyFrom(Must(WithCapacity(1001, 3))); !errors.Is(err, ErrStorageDifference) {
+		t.Errorf("expected %v, got %v", ErrStorageDifference, err)
+	}
+	if err := f.CopyFrom(Must(WithCapacity(1000, 3), WithFNV())); !errors.Is(err, ErrHasherDifference) {
+		t.Errorf("expected %v, got %v", ErrHasherDifference, err)
+	}
+	if err := f.CopyFrom(f); err != nil || !f.Exists([]byte("item")) || f.Count() != 1 {
+		t.Errorf("expected copying from itself changes nothing, got %v", err)
+	}
+	if !f.Exists([]byte("item")) {
+		t.Errorf("expected the filter is not changed")
+	}
+}
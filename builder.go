@@ -0,0 +1,303 @@
+package bf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+const builderBatchSize = 1024
+const defaultProgressEvery = 1 << 16
+
+/*
+Builder creates a BloomFilter from many items by hashing them in parallel.
+Bits of the built-in Storage are set atomically, any other Storage is filled
+per worker by a Storage of the same StorageFactory then merged by BatchUnion,
+or by Get and Set if it is not implemented. The hasher must be safe for
+concurrent use, the built-in hashers are.
+
+The built filter has AddCount equal to the number of items, DistinctCount is
+estimated from the number of set bits WithDistinctCount.
+*/
+type Builder struct {
+	// Config and Options are given to New.
+	Config  Config
+	Options []OptionFunc
+
+	// Workers is the number of goroutines, runtime.GOMAXPROCS(0) if it is not
+	// positive.
+	Workers int
+
+	// Progress is called with the number of added items after every
+	// ProgressEvery items (65536 if it is not positive) and once at the end.
+	// It is called from worker goroutines but never concurrently.
+	Progress      func(added int64)
+	ProgressEvery int
+}
+
+// FromSlice builds a filter of the given items.
+func (b Builder) FromSlice(ctx context.Context, items [][]byte) (BloomFilter, error) {
+	var next int64
+	return b.build(ctx, func(context.Context) ([][]byte, error) {
+		end := atomic.AddInt64(&next, builderBatchSize)
+		start := end - builderBatchSize
+		if start >= int64(len(items)) {
+			return nil, io.EOF
+		}
+		if end > int64(len(items)) {
+			end = int64(len(items))
+		}
+		return items[start:end], nil
+	})
+}
+
+// FromChannel builds a filter of items received from the channel until it is
+// closed.
+func (b Builder) FromChannel(ctx context.Context, items <-chan []byte) (BloomFilter, error) {
+	return b.build(ctx, func(ctx context.Context) ([][]byte, error) {
+		var batch [][]byte
+		select {
+		case item, ok := <-items:
+			if !ok {
+				return nil, io.EOF
+			}
+			batch = append(batch, item)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		// take what is ready without waiting for a full batch
+		for len(batch) < builderBatchSize {
+			select {
+			case item, ok := <-items:
+				if !ok {
+					return batch, nil
+				}
+				batch = append(batch, item)
+			default:
+				return batch, nil
+			}
+		}
+		return batch, nil
+	})
+}
+
+// FromLines builds a filter of every line of the reader, a line ends with "\n"
+// or "\r\n" which is not a part of the item.
+func (b Builder) FromLines(ctx context.Context, r io.Reader) (BloomFilter, error) {
+	br := bufio.NewReaderSize(r, 1<<16)
+	return b.fromReader(ctx, func() ([]byte, error) {
+		line, err := br.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+
+		line = bytes.TrimSuffix(line, []byte{'\n'})
+		return bytes.TrimSuffix(line, []byte{'\r'}), nil
+	})
+}
+
+// FromLengthPrefixed builds a filter of items of the reader, every item is
+// prefixed by its length encoded as uvarint.
+func (b Builder) FromLengthPrefixed(ctx context.Context, r io.Reader) (BloomFilter, error) {
+	br := bufio.NewReaderSize(r, 1<<16)
+	return b.fromReader(ctx, func() ([]byte, error) {
+		size, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		if size > math.MaxInt32 {
+			return nil, ErrInvalidSerializedData
+		}
+
+		// the item grows while it is read so a corrupted length does not
+		// allocate everything at once
+		var item bytes.Buffer
+		if _, err = io.CopyN(&item, br, int64(size)); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return item.Bytes(), nil
+	})
+}
+
+func (b Builder) fromReader(ctx context.Context, read func() ([]byte, error)) (BloomFilter, error) {
+	var mu sync.Mutex
+	return b.build(ctx, func(context.Context) ([][]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		batch := make([][]byte, 0, builderBatchSize)
+		for len(batch) < builderBatchSize {
+			item, err := read()
+			if err == io.EOF && len(batch) > 0 {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			batch = append(batch, item)
+		}
+		return batch, nil
+	})
+}
+
+// build runs workers which call next until it returns io.EOF, next must be
+// safe for concurrent use.
+func (b Builder) build(ctx context.Context, next func(context.Context) ([][]byte, error)) (BloomFilter, error) {
+	f, err := New(b.Config, b.Options...)
+	if err != nil {
+		return nil, err
+	}
+	target := innerBloomFilter(f).(*bloomFilter)
+
+	workers := b.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	sets, locals, err := b.sinks(target, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var first error
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+
+	p := builderProgress{fn: b.Progress, every: int64(b.ProgressEvery)}
+	if p.every <= 0 {
+		p.every = defaultProgressEvery
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(set func(uint32)) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				batch, err := next(ctx)
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					fail(err)
+					return
+				}
+
+				for _, item := range batch {
+					for _, key := range target.hasher.Hash(item, 1)[0] {
+						set(target.index(key))
+					}
+				}
+				p.add(len(batch))
+			}
+			fail(ctx.Err())
+		}(sets[w])
+	}
+	wg.Wait()
+
+	if first != nil {
+		return nil, first
+	}
+
+	for _, local := range locals {
+		mergeStorage(target.storage, local)
+	}
+	target.count = int(p.added)
+	if target.option.distinctCount {
+		target.distinct = target.estimateDistinct()
+	}
+	p.done()
+	return f, nil
+}
+
+// sinks returns a function which sets a bit for every worker, and Storages
+// which must be merged into the target after workers are done.
+func (b Builder) sinks(target *bloomFilter, workers int) ([]func(uint32), []Storage, error) {
+	sets := make([]func(uint32), workers)
+	if s, ok := target.storage.(*bitset); ok {
+		for i := range sets {
+			sets[i] = s.setAtomic
+		}
+		return sets, nil, nil
+	}
+
+	locals := make([]Storage, workers)
+	for i := range locals {
+		s, err := target.option.storageFactory.Make(target.storage.Capacity())
+		if err != nil {
+			return nil, nil, err
+		}
+		if s == nil {
+			return nil, nil, ErrNilStorage
+		}
+		locals[i] = s
+		sets[i] = s.Set
+	}
+	return sets, locals, nil
+}
+
+func mergeStorage(dst, src Storage) {
+	if bu, ok := dst.(BatchUnion); ok {
+		bu.Union(src)
+		return
+	}
+
+	for i := uint32(0); i < src.Capacity(); i++ {
+		if src.Get(i) {
+			dst.Set(i)
+		}
+	}
+}
+
+// builderProgress keeps added first, 64-bit atomic operations need 8-byte
+// alignment which only the first word of a struct has on 32-bit platforms.
+type builderProgress struct {
+	added    int64
+	reported int64
+	mu       sync.Mutex
+	fn       func(added int64)
+	every    int64
+}
+
+func (p *builderProgress) add(n int) {
+	added := atomic.AddInt64(&p.added, int64(n))
+	if p.fn == nil || added/p.every == (added-int64(n))/p.every {
+		return
+	}
+	p.report(added)
+}
+
+func (p *builderProgress) done() {
+	if p.fn != nil {
+		p.report(atomic.LoadInt64(&p.added))
+	}
+}
+
+// report calls fn with increasing numbers only, workers could finish batches
+// out of order.
+func (p *builderProgress) report(added int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if added > p.reported {
+		p.reported = added
+		p.fn(added)
+	}
+}
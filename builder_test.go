@@ -0,0 +1,208 @@
+package bf
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+func makeItemsForBuilderTest(n int) [][]byte {
+	items := make([][]byte, n)
+	for i := range items {
+		items[i] = []byte(fmt.Sprintf("item-%d", i))
+	}
+	return items
+}
+
+func assertBuiltFilter(t *testing.T, f BloomFilter, err error, items [][]byte, opts ...OptionFunc) {
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	expected := Must(WithCapacity(1<<16, 3), opts...)
+	for _, item := range items {
+		expected.Add(item)
+	}
+	for i := uint32(0); i < expected.Storage().Capacity(); i++ {
+		if f.Storage().Get(i) != expected.Storage().Get(i) {
+			t.Fatalf("unexpected bit %v", i)
+		}
+	}
+	if f.AddCount() != len(items) {
+		t.Errorf("expected count %v, got %v", len(items), f.AddCount())
+	}
+}
+
+func TestBuilder_FromSlice(t *testing.T) {
+	items := makeItemsForBuilderTest(5000)
+	cases := []struct {
+		name    string
+		workers int
+		opts    []OptionFunc
+	}{
+		{name: "default workers"},
+		{name: "1 worker", workers: 1},
+		{name: "more workers than batches", workers: 16},
+		{name: "fnv concurrent", workers: 4, opts: []OptionFunc{WithFNV(), WithConcurrency()}},
+		{name: "mmap", workers: 4, opts: []OptionFunc{WithStorage(MmapStorageFactory{})}},
+		{name: "custom storage", workers: 4, opts: []OptionFunc{WithStorage(sliceStorageFactory{})}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := Builder{Config: WithCapacity(1<<16, 3), Options: tc.opts, Workers: tc.workers}
+			f, err := b.FromSlice(context.Background(), items)
+			assertBuiltFilter(t, f, err, items, tc.opts...)
+		})
+	}
+}
+
+func TestBuilder_DistinctCount(t *testing.T) {
+	items := makeItemsForBuilderTest(5000)
+	b := Builder{Config: WithCapacity(1<<16, 3), Options: []OptionFunc{WithDistinctCount()}}
+	f, _ := b.FromSlice(context.Background(), append(items, items...))
+	if f.AddCount() != 10000 || math.Abs(float64(f.DistinctCount()-5000)) > 100 {
+		t.Errorf("expected count 10000 and distinct count about 5000, got %v, %v", f.AddCount(), f.DistinctCount())
+	}
+}
+
+func TestBuilder_FromChannel(t *testing.T) {
+	items := makeItemsForBuilderTest(5000)
+	ch := make(chan []byte, 100)
+	go func() {
+		for _, item := range items {
+			ch <- item
+		}
+		close(ch)
+	}()
+
+	f, err := Builder{Config: WithCapacity(1<<16, 3), Workers: 4}.FromChannel(context.Background(), ch)
+	assertBuiltFilter(t, f, err, items)
+}
+
+func TestBuilder_FromLines(t *testing.T) {
+	items := makeItemsForBuilderTest(3000)
+	var sb strings.Builder
+	for i, item := range items {
+		sb.Write(item)
+		if i%2 == 0 {
+			sb.WriteString("\r")
+		}
+		if i < len(items)-1 {
+			sb.WriteString("\n")
+		}
+	}
+
+	f, err := Builder{Config: WithCapacity(1<<16, 3)}.FromLines(context.Background(), strings.NewReader(sb.String()))
+	assertBuiltFilter(t, f, err, items)
+
+	f, err = Builder{Config: WithCapacity(1<<16, 3)}.FromLines(context.Background(), strings.NewReader("a\n\nb\n"))
+	assertBuiltFilter(t, f, err, [][]byte{[]byte("a"), {}, []byte("b")})
+}
+
+func TestBuilder_FromLengthPrefixed(t *testing.T) {
+	items := append(makeItemsForBuilderTest(3000), []byte{}, []byte("line\nbreak"))
+	var buf bytes.Buffer
+	for _, item := range items {
+		buf.Write(binary.AppendUvarint(nil, uint64(len(item))))
+		buf.Write(item)
+	}
+	data := buf.Bytes()
+
+	f, err := Builder{Config: WithCapacity(1<<16, 3)}.FromLengthPrefixed(context.Background(), bytes.NewReader(data))
+	assertBuiltFilter(t, f, err, items)
+
+	for _, size := range []int{len(data) - 1, len(data) - 13} {
+		_, err = Builder{Config: WithCapacity(1<<16, 3)}.FromLengthPrefixed(context.Background(), bytes.NewReader(data[:size]))
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("size %v: expected io.ErrUnexpectedEOF, got %v", size, err)
+		}
+	}
+
+	huge := binary.AppendUvarint(nil, math.MaxUint64)
+	_, err = Builder{Config: WithCapacity(1<<16, 3)}.FromLengthPrefixed(context.Background(), bytes.NewReader(huge))
+	if !errors.Is(err, ErrInvalidSerializedData) {
+		t.Errorf("expected ErrInvalidSerializedData, got %v", err)
+	}
+}
+
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestBuilder_ReturnsErrOfReader(t *testing.T) {
+	expected := errors.New("broken")
+	r := &failingReader{data: []byte("a\nb\nc"), err: expected}
+	f, err := Builder{Config: WithCapacity(1<<16, 3)}.FromLines(context.Background(), r)
+	if f != nil || !errors.Is(err, expected) {
+		t.Errorf("expected %v, got %v, %v", expected, f, err)
+	}
+
+	if _, err = (Builder{}).FromSlice(context.Background(), nil); !errors.Is(err, ErrNilConfig) {
+		t.Errorf("expected ErrNilConfig, got %v", err)
+	}
+}
+
+func TestBuilder_Cancel(t *testing.T) {
+	items := makeItemsForBuilderTest(100_000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f, err := Builder{Config: WithCapacity(1<<16, 3)}.FromSlice(ctx, items)
+	if f != nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v, %v", f, err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	b := Builder{Config: WithCapacity(1<<16, 3), ProgressEvery: 1000, Progress: func(added int64) {
+		cancel()
+	}}
+	if _, err = b.FromSlice(ctx, items); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	// a channel which is never closed
+	ch := make(chan []byte)
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		ch <- []byte("a")
+		cancel()
+	}()
+	if _, err = (Builder{Config: WithCapacity(1<<16, 3)}).FromChannel(ctx, ch); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBuilder_Progress(t *testing.T) {
+	var reported []int64
+	b := Builder{Config: WithCapacity(1<<16, 3), Workers: 4, ProgressEvery: 5000, Progress: func(added int64) {
+		reported = append(reported, added)
+	}}
+	_, _ = b.FromSlice(context.Background(), makeItemsForBuilderTest(50_500))
+
+	if len(reported) < 2 || reported[len(reported)-1] != 50_500 {
+		t.Fatalf("expected progress ends with 50500, got %v", reported)
+	}
+	for i := 1; i < len(reported); i++ {
+		if reported[i] <= reported[i-1] {
+			t.Errorf("expected increasing progress, got %v", reported)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+/*
+Command bf creates, fills, queries and inspects Bloom Filter files which could
+be loaded by bf.Unmarshal.
+
+Usage:
+
+	bf create [--error 0.001 --items 1e6 | --capacity 65536 --hashes 5] [--hasher sha|fnv] [--distinct] FILE
+	bf add FILE [INPUT...]
+	bf exists FILE [INPUT...]
+	bf union OUT FILE FILE...
+	bf intersect OUT FILE FILE...
+	bf info FILE
+
+Items are read line by line from the INPUT files, or from stdin if no INPUT is
+given or INPUT is "-". Empty lines are ignored.
+*/
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/toniphan21/go-bf"
+)
+
+const usage = `Usage:
+  bf create [--error 0.001 --items 1e6 | --capacity 65536 --hashes 5] [--hasher sha|fnv] [--distinct] FILE
+  bf add FILE [INPUT...]
+  bf exists FILE [INPUT...]
+  bf union OUT FILE FILE...
+  bf intersect OUT FILE FILE...
+  bf info FILE
+`
+
+const maxLineSize = 1024 * 1024
+
+var errUsage = errors.New("invalid arguments")
+
+type command struct {
+	args   []string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	c := command{args: args[1:], stdin: stdin, stdout: stdout, stderr: stderr}
+	var err error
+	switch args[0] {
+	case "create":
+		err = c.create()
+	case "add":
+		err = c.add()
+	case "exists":
+		err = c.exists()
+	case "union":
+		err = c.merge(bf.BloomFilter.Union)
+	case "intersect":
+		err = c.merge(bf.BloomFilter.Intersect)
+	case "info":
+		err = c.info()
+	default:
+		err = errUsage
+	}
+
+	if errors.Is(err, errUsage) {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "bf %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func (c *command) create() error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	errorRate := fs.Float64("error", 0, "requested error rate, used with --items")
+	items := fs.Float64("items", 0, "expected number of items, used with --error")
+	capacity := fs.Float64("capacity", 0, "storage capacity in bits, used with --hashes")
+	hashes := fs.Uint("hashes", 0, "number of hash functions, used with --capacity")
+	hasher := fs.String("hasher", "sha", "hashing strategy: sha or fnv")
+	distinct := fs.Bool("distinct", false, "count distinct items instead of added lines")
+	if err := fs.Parse(c.args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	var cf bf.Config
+	if *capacity > 0 || *hashes > 0 {
+		if *errorRate > 0 || *items > 0 || *capacity > math.MaxUint32 || *hashes > math.MaxUint8 {
+			return errUsage
+		}
+		cf = bf.WithCapacity(uint32(*capacity), byte(*hashes))
+	} else {
+		if *items > math.MaxUint32 {
+			return errUsage
+		}
+		cf = bf.WithAccuracy(*errorRate, uint32(*items))
+	}
+
+	var opt bf.OptionFunc
+	switch *hasher {
+	case "sha":
+		opt = bf.WithSHA()
+	case "fnv":
+		opt = bf.WithFNV()
+	default:
+		return fmt.Errorf("unknown hasher %q", *hasher)
+	}
+
+	opts := []bf.OptionFunc{opt}
+	if *distinct {
+		opts = append(opts, bf.WithDistinctCount())
+	}
+
+	f, err := bf.New(cf, opts...)
+	if err != nil {
+		return err
+	}
+	return writeFilter(fs.Arg(0), f)
+}
+
+func (c *command) add() error {
+	if len(c.args) < 1 {
+		return errUsage
+	}
+
+	f, err := readFilter(c.args[0])
+	if err != nil {
+		return err
+	}
+
+	err = c.eachLine(c.args[1:], func(line []byte) {
+		f.Add(line)
+	})
+	if err != nil {
+		return err
+	}
+	return writeFilter(c.args[0], f)
+}
+
+func (c *command) exists() error {
+	if len(c.args) < 1 {
+		return errUsage
+	}
+
+	f, err := readFilter(c.args[0])
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(c.stdout)
+	err = c.eachLine(c.args[1:], func(line []byte) {
+		fmt.Fprintf(w, "%t\t%s\n", f.Exists(line), line)
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (c *command) merge(op func(bf.BloomFilter, bf.ReadOnlyFilter) error) error {
+	if len(c.args) < 3 {
+		return errUsage
+	}
+
+	target, err := readFilter(c.args[1])
+	if err != nil {
+		return err
+	}
+
+	for _, name := range c.args[2:] {
+		other, err := readFilter(name)
+		if err != nil {
+			return err
+		}
+		if err = op(target, other); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return writeFilter(c.args[0], target)
+}
+
+func (c *command) info() error {
+	if len(c.args) != 1 {
+		return errUsage
+	}
+
+	f, err := readFilter(c.args[0])
+	if err != nil {
+		return err
+	}
+
+	capacity := f.Storage().Capacity()
+	set := countSetBits(f)
+	ratio := float64(set) / float64(capacity)
+	k := f.Config().NumberOfHashFunctions()
+
+	fmt.Fprintln(c.stdout, f.Config().Info())
+	fmt.Fprintln(c.stdout, "Filter")
+	if f.AddCount() < 0 {
+		fmt.Fprintln(c.stdout, "  - Number of added items: unknown")
+	} else {
+		fmt.Fprintf(c.stdout, "  - Number of added items: %d\n", f.AddCount())
+	}
+	if f.DistinctCount() >= 0 {
+		fmt.Fprintf(c.stdout, "  - Number of distinct items: %d\n", f.DistinctCount())
+	}
+	fmt.Fprintf(c.stdout, "  - Number of set bits: %d (%#.3f%%)\n", set, ratio*100)
+	fmt.Fprintf(c.stdout, "  - Current error rate: %#.5f%%\n", math.Pow(ratio, float64(k))*100)
+	return nil
+}
+
+func (c *command) eachLine(inputs []string, fn func(line []byte)) error {
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+
+	for _, name := range inputs {
+		if err := c.eachLineOf(name, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eachLineOf reads lines of a file or stdin if the name is "-", the file is
+// closed before the next one is opened.
+func (c *command) eachLineOf(name string, fn func(line []byte)) error {
+	r := c.stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); len(line) > 0 {
+			fn(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func countSetBits(f bf.BloomFilter) uint32 {
+	var count uint32
+	storage := f.Storage()
+	for i := uint32(0); i < storage.Capacity(); i++ {
+		if storage.Get(i) {
+			count++
+		}
+	}
+	return count
+}
+
+func readFilter(name string) (bf.BloomFilter, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := bf.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return f, nil
+}
+
+func writeFilter(name string, f bf.BloomFilter) error {
+	data, err := bf.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	tmp := name + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
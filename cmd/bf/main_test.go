@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runForTest(t *testing.T, stdin string, args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestRun_PrintsUsageIfArgumentsAreInvalid(t *testing.T) {
+	cases := [][]string{
+		{},
+		{"unknown"},
+		{"create"},
+		{"create", "--error", "0.01", "--capacity", "100", "out.bf"},
+		{"add"},
+		{"exists"},
+		{"union", "out.bf", "a.bf"},
+		{"info"},
+	}
+
+	for _, args := range cases {
+		_, stderr, code := runForTest(t, "", args...)
+		if code != 2 {
+			t.Errorf("%v: expected exit code 2, got %d", args, code)
+		}
+		if !strings.HasPrefix(stderr, "Usage:") {
+			t.Errorf("%v: expected usage, got %v", args, stderr)
+		}
+	}
+}
+
+func TestRun_CreateAddExistsAndInfo(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "out.bf")
+	input := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(input, []byte("a\nb\n\nc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, stderr, code := runForTest(t, "", "create", "--error", "0.001", "--items", "1e3", "--hasher", "fnv", file); code != 0 {
+		t.Fatalf("create failed: %v", stderr)
+	}
+	if _, stderr, code := runForTest(t, "", "add", file, input); code != 0 {
+		t.Fatalf("add failed: %v", stderr)
+	}
+	if _, stderr, code := runForTest(t, "d\n", "add", file); code != 0 {
+		t.Fatalf("add from stdin failed: %v", stderr)
+	}
+
+	stdout, stderr, code := runForTest(t, "a\nd\nnot-found\n", "exists", file)
+	if code != 0 {
+		t.Fatalf("exists failed: %v", stderr)
+	}
+	expected := "true\ta\ntrue\td\nfalse\tnot-found\n"
+	if stdout != expected {
+		t.Errorf("expected %q, got %q", expected, stdout)
+	}
+
+	stdout, stderr, code = runForTest(t, "", "info", file)
+	if code != 0 {
+		t.Fatalf("info failed: %v", stderr)
+	}
+	for _, s := range []string{"Config WithAccuracy()", "Expected number of items: 1000", "Number of added items: 4"} {
+		if !strings.Contains(stdout, s) {
+			t.Errorf("expected info contains %q, got %v", s, stdout)
+		}
+	}
+}
+
+func TestRun_CreateWithDistinct(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "out.bf")
+	runForTest(t, "", "create", "--capacity", "4096", "--hashes", "3", "--distinct", file)
+	runForTest(t, "a\nb\na\n", "add", file)
+
+	stdout, _, _ := runForTest(t, "", "info", file)
+	for _, s := range []string{"Number of added items: 3", "Number of distinct items: 2"} {
+		if !strings.Contains(stdout, s) {
+			t.Errorf("expected info contains %q, got %v", s, stdout)
+		}
+	}
+}
+
+func TestRun_UnionAndIntersect(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.bf"), filepath.Join(dir, "b.bf")
+	union, intersect := filepath.Join(dir, "union.bf"), filepath.Join(dir, "intersect.bf")
+	for _, name := range []string{a, b} {
+		if _, stderr, code := runForTest(t, "", "create", "--capacity", "4096", "--hashes", "3", name); code != 0 {
+			t.Fatalf("create failed: %v", stderr)
+		}
+	}
+	runForTest(t, "only-a\nshared\n", "add", a)
+	runForTest(t, "only-b\nshared\n", "add", b)
+
+	if _, stderr, code := runForTest(t, "", "union", union, a, b); code != 0 {
+		t.Fatalf("union failed: %v", stderr)
+	}
+	if _, stderr, code := runForTest(t, "", "intersect", intersect, a, b); code != 0 {
+		t.Fatalf("intersect failed: %v", stderr)
+	}
+
+	stdout, _, _ := runForTest(t, "only-a\nonly-b\nshared\n", "exists", union)
+	if stdout != "true\tonly-a\ntrue\tonly-b\ntrue\tshared\n" {
+		t.Errorf("unexpected union result %q", stdout)
+	}
+	stdout, _, _ = runForTest(t, "only-a\nonly-b\nshared\n", "exists", intersect)
+	if stdout != "false\tonly-a\nfalse\tonly-b\ntrue\tshared\n" {
+		t.Errorf("unexpected intersect result %q", stdout)
+	}
+}
+
+func TestRun_ReturnsErrorIfFilesAreIncompatible(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.bf"), filepath.Join(dir, "b.bf")
+	runForTest(t, "", "create", "--capacity", "4096", "--hashes", "3", a)
+	runForTest(t, "", "create", "--capacity", "2048", "--hashes", "3", b)
+
+	_, stderr, code := runForTest(t, "", "union", filepath.Join(dir, "out.bf"), a, b)
+	if code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr, "capacity 4096 != 2048") {
+		t.Errorf("expected capacity error, got %v", stderr)
+	}
+}
@@ -0,0 +1,135 @@
+package bf
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ParameterCapacity              = "capacity"
+	ParameterNumberOfHashFunctions = "number of hash functions"
+	ParameterKeySize               = "key size"
+	ParameterHasher                = "hasher"
+	ParameterStorage               = "storage"
+	ParameterIndexMapping          = "index mapping"
+)
+
+// Mismatch is a parameter which has different values A and B in two filters.
+type Mismatch struct {
+	Parameter string
+	A, B      any
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%v %v != %v", m.Parameter, m.A, m.B)
+}
+
+/*
+IncompatibleError lists every parameter which differs between two filters. It
+matches ErrStorageDifference if the capacity or storage differs, and
+ErrHasherDifference if the hasher, number of hash functions, key size or index
+mapping differs, so it could be checked by errors.Is as well as errors.As.
+*/
+type IncompatibleError struct {
+	Mismatches []Mismatch
+}
+
+func (e *IncompatibleError) Error() string {
+	mismatches := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		mismatches[i] = m.String()
+	}
+	return "filters are not compatible: " + strings.Join(mismatches, ", ")
+}
+
+func (e *IncompatibleError) Is(target error) bool {
+	for _, m := range e.Mismatches {
+		switch m.Parameter {
+		case ParameterCapacity, ParameterStorage:
+			if target == ErrStorageDifference {
+				return true
+			}
+		default:
+			if target == ErrHasherDifference {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+Compatible returns nil if filters a and b could be merged by Union, Intersect
+and other functions which combine filters, otherwise an *IncompatibleError
+explains which parameters differ. A filter without Storage, for example a
+server.Client after a failed request, is never compatible.
+*/
+func Compatible(a, b ReadOnlyFilter) error {
+	if a == nil || b == nil {
+		return ErrNilBloomFilter
+	}
+
+	var mismatches []Mismatch
+	add := func(parameter string, va, vb any) {
+		mismatches = append(mismatches, Mismatch{Parameter: parameter, A: va, B: vb})
+	}
+
+	as, bs := a.Storage(), b.Storage()
+	if as == nil || bs == nil {
+		add(ParameterStorage, kindOf(as), kindOf(bs))
+	} else {
+		if as.Capacity() != bs.Capacity() {
+			add(ParameterCapacity, as.Capacity(), bs.Capacity())
+		}
+		if kindOf(as) != kindOf(bs) || (len(mismatches) == 0 && !as.Equals(bs)) {
+			add(ParameterStorage, kindOf(as), kindOf(bs))
+		}
+	}
+
+	n := len(mismatches)
+	am, aOk := indexMappingOf(a)
+	bm, bOk := indexMappingOf(b)
+	mappingDiffers := aOk && bOk && am != bm
+	if ac, bc := a.Config(), b.Config(); ac != nil && bc != nil {
+		if ac.NumberOfHashFunctions() != bc.NumberOfHashFunctions() {
+			add(ParameterNumberOfHashFunctions, ac.NumberOfHashFunctions(), bc.NumberOfHashFunctions())
+		}
+		// the key size follows the index mapping if it differs
+		if ak, bk := keySizeOf(a, ac), keySizeOf(b, bc); ak != bk && !mappingDiffers {
+			add(ParameterKeySize, ak, bk)
+		}
+	}
+	if mappingDiffers {
+		add(ParameterIndexMapping, am, bm)
+	}
+
+	ah, bh := a.Hasher(), b.Hasher()
+	if hasherName(ah) != hasherName(bh) {
+		add(ParameterHasher, hasherName(ah), hasherName(bh))
+	}
+	if len(mismatches) == n && ah != nil && !ah.Equals(bh) {
+		add(ParameterHasher, hasherName(ah), hasherName(bh))
+	}
+
+	if len(mismatches) > 0 {
+		return &IncompatibleError{Mismatches: mismatches}
+	}
+	return nil
+}
+
+func kindOf(s Storage) string {
+	if _, ok := s.(*bitset); ok {
+		return "bitset"
+	}
+	return fmt.Sprintf("%T", s)
+}
+
+func hasherName(h Hasher) string {
+	switch h.(type) {
+	case *shaHasher:
+		return "sha"
+	case *fnvHasher:
+		return "fnv"
+	}
+	return fmt.Sprintf("%T", h)
+}
@@ -0,0 +1,173 @@
+package bf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type seededHasher struct {
+	shaHasher
+	seed uint64
+}
+
+func (s *seededHasher) Equals(other Hasher) bool {
+	o, ok := other.(*seededHasher)
+	return ok && o.seed == s.seed && o.hasher == s.hasher
+}
+
+type seededHasherFactory struct {
+	seed uint64
+}
+
+func (f seededHasherFactory) Make(numberOfHashFunctions, hashSizeInBits byte) Hasher {
+	h := shaHasherFactory{}.Make(numberOfHashFunctions, hashSizeInBits).(*shaHasher)
+	return &seededHasher{shaHasher: *h, seed: f.seed}
+}
+
+func TestCompatible(t *testing.T) {
+	a := Must(WithCapacity(1024, 3))
+	cases := []struct {
+		name       string
+		a, b       BloomFilter
+		parameters []string
+		is         []error
+		message    string
+	}{
+		{name: "same", a: a, b: Must(WithCapacity(1024, 3))},
+		{name: "concurrent", a: a, b: Must(WithCapacity(1024, 3), WithConcurrency())},
+		{
+			name:       "capacity",
+			a:          a,
+			b:          Must(WithCapacity(1000, 3)),
+			parameters: []string{ParameterCapacity},
+			is:         []error{ErrStorageDifference},
+			message:    "filters are not compatible: capacity 1024 != 1000",
+		},
+		{
+			name:       "capacity and key size",
+			a:          Must(WithCapacity(1024, 3), WithIndexMapping(IndexMappingModulo)),
+			b:          Must(WithCapacity(4096, 3), WithIndexMapping(IndexMappingModulo)),
+			parameters: []string{ParameterCapacity, ParameterKeySize},
+			is:         []error{ErrStorageDifference, ErrHasherDifference},
+			message:    "filters are not compatible: capacity 1024 != 4096, key size 10 != 12",
+		},
+		{
+			name:       "capacity with 32-bit keys of multiply-shift",
+			a:          a,
+			b:          Must(WithCapacity(4096, 3)),
+			parameters: []string{ParameterCapacity},
+			is:         []error{ErrStorageDifference},
+			message:    "filters are not compatible: capacity 1024 != 4096",
+		},
+		{
+			name:       "number of hash functions",
+			a:          a,
+			b:          Must(WithCapacity(1024, 4)),
+			parameters: []string{ParameterNumberOfHashFunctions},
+			is:         []error{ErrHasherDifference},
+		},
+		{
+			name:       "hasher",
+			a:          a,
+			b:          Must(WithCapacity(1024, 3), WithFNV()),
+			parameters: []string{ParameterHasher},
+			is:         []error{ErrHasherDifference},
+			message:    "filters are not compatible: hasher sha != fnv",
+		},
+		{
+			name:       "index mapping",
+			a:          a,
+			b:          Must(WithCapacity(1024, 3), WithIndexMapping(IndexMappingModulo)),
+			parameters: []string{ParameterIndexMapping},
+			is:         []error{ErrHasherDifference},
+			message:    "filters are not compatible: index mapping multiply-shift != modulo",
+		},
+		{
+			name:       "hasher which is not equal",
+			a:          Must(WithCapacity(1024, 3), WithHasher(seededHasherFactory{seed: 1})),
+			b:          Must(WithCapacity(1024, 3), WithHasher(seededHasherFactory{seed: 2})),
+			parameters: []string{ParameterHasher},
+			is:         []error{ErrHasherDifference},
+		},
+		{
+			name:       "storage",
+			a:          a,
+			b:          Must(WithCapacity(1024, 3), WithStorage(sliceStorageFactory{})),
+			parameters: []string{ParameterStorage},
+			is:         []error{ErrStorageDifference},
+			message:    "filters are not compatible: storage bitset != *bf.sliceStorage",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Compatible(tc.a, tc.b)
+			if len(tc.parameters) == 0 {
+				if err != nil {
+					t.Errorf("expected nil, got %v", err)
+				}
+				return
+			}
+
+			var ie *IncompatibleError
+			if !errors.As(err, &ie) {
+				t.Fatalf("expected IncompatibleError, got %v", err)
+			}
+			var parameters []string
+			for _, m := range ie.Mismatches {
+				parameters = append(parameters, m.Parameter)
+			}
+			if strings.Join(parameters, ",") != strings.Join(tc.parameters, ",") {
+				t.Errorf("expected %v, got %v", tc.parameters, parameters)
+			}
+			for _, target := range tc.is {
+				if !errors.Is(err, target) {
+					t.Errorf("expected %v is %v", err, target)
+				}
+			}
+			if tc.message != "" && err.Error() != tc.message {
+				t.Errorf("expected %q, got %q", tc.message, err.Error())
+			}
+		})
+	}
+}
+
+func TestCompatible_ReturnsErrIfBloomFilterIsNil(t *testing.T) {
+	if err := Compatible(nil, Must(WithCapacity(1024, 3))); !errors.Is(err, ErrNilBloomFilter) {
+		t.Errorf("expected %v, got %v", ErrNilBloomFilter, err)
+	}
+}
+
+type nilStorageFilter struct {
+	BloomFilter
+}
+
+func (nilStorageFilter) Storage() Storage {
+	return nil
+}
+
+func TestCompatible_ReturnsErrStorageDifferenceIfStorageIsNil(t *testing.T) {
+	a := Must(WithCapacity(1024, 3))
+	b := nilStorageFilter{Must(WithCapacity(1024, 3))}
+
+	for _, err := range []error{Compatible(a, b), Compatible(b, a), a.Union(b), a.Intersect(b), a.CopyFrom(b)} {
+		if !errors.Is(err, ErrStorageDifference) {
+			t.Errorf("expected %v, got %v", ErrStorageDifference, err)
+		}
+	}
+	if _, err := UnionAll(a, b); !errors.Is(err, ErrStorageDifference) {
+		t.Errorf("expected %v, got %v", ErrStorageDifference, err)
+	}
+}
+
+func TestUnion_ReturnsIncompatibleError(t *testing.T) {
+	a := Must(WithCapacity(1024, 3))
+	var ie *IncompatibleError
+	if err := a.Union(Must(WithCapacity(1024, 3), WithFNV())); !errors.As(err, &ie) {
+		t.Errorf("expected IncompatibleError, got %v", err)
+	}
+	if err := a.Intersect(Must(WithCapacity(512, 3))); !errors.As(err, &ie) {
+		t.Errorf("expected IncompatibleError, got %v", err)
+	}
+}
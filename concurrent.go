@@ -0,0 +1,153 @@
+package bf
+
+import "sync"
+
+type concurrentBloomFilter struct {
+	mu     sync.RWMutex
+	filter *bloomFilter
+}
+
+func (c *concurrentBloomFilter) Add(item []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.filter.Add(item)
+}
+
+func (c *concurrentBloomFilter) Exists(item []byte) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.filter.Exists(item)
+}
+
+func (c *concurrentBloomFilter) TestAndAdd(item []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.filter.TestAndAdd(item)
+}
+
+// Keys does not lock, the hasher and capacity of a filter never change.
+func (c *concurrentBloomFilter) Keys(item []byte) KeyHandle {
+	return c.filter.Keys(item)
+}
+
+func (c *concurrentBloomFilter) AddKeys(h KeyHandle) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.filter.AddKeys(h)
+}
+
+func (c *concurrentBloomFilter) ExistsKeys(h KeyHandle) (bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.filter.ExistsKeys(h)
+}
+
+func (c *concurrentBloomFilter) Count() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.filter.Count()
+}
+
+func (c *concurrentBloomFilter) AddCount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.filter.AddCount()
+}
+
+func (c *concurrentBloomFilter) DistinctCount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.filter.DistinctCount()
+}
+
+func (c *concurrentBloomFilter) Storage() Storage {
+	return c.filter.Storage()
+}
+
+func (c *concurrentBloomFilter) Hasher() Hasher {
+	return c.filter.Hasher()
+}
+
+func (c *concurrentBloomFilter) Config() Config {
+	return c.filter.Config()
+}
+
+func (c *concurrentBloomFilter) Intersect(other ReadOnlyFilter) error {
+	o, err := c.stable(other)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.filter.Intersect(o)
+}
+
+func (c *concurrentBloomFilter) Union(other ReadOnlyFilter) error {
+	o, err := c.stable(other)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.filter.Union(o)
+}
+
+func (c *concurrentBloomFilter) Clone() (BloomFilter, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	r, err := c.filter.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &concurrentBloomFilter{filter: r.(*bloomFilter)}, nil
+}
+
+func (c *concurrentBloomFilter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.filter.Reset()
+}
+
+func (c *concurrentBloomFilter) CopyFrom(other ReadOnlyFilter) error {
+	o, err := c.stable(other)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.filter.CopyFrom(o)
+}
+
+// stable returns a filter which could be read while holding the lock of c,
+// other concurrent and durable filters are snapshotted under their own lock to
+// avoid deadlock. The snapshot shares pages or copies them into the Go heap, so
+// a temporary Storage of MmapStorageFactory is never left unreleased.
+func (c *concurrentBloomFilter) stable(other ReadOnlyFilter) (ReadOnlyFilter, error) {
+	if other == ReadOnlyFilter(c) {
+		return c.filter, nil
+	}
+	return stable(other), nil
+}
+
+func innerBloomFilter(f BloomFilter) BloomFilter {
+	if c, ok := f.(*concurrentBloomFilter); ok {
+		return c.filter
+	}
+	return f
+}
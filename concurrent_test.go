@@ -0,0 +1,218 @@
+package bf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestWithConcurrency(t *testing.T) {
+	opt := &Option{}
+	fn := WithConcurrency()
+	fn(opt)
+
+	if !opt.concurrent {
+		t.Errorf("Expected concurrent option is enabled")
+	}
+
+	f := Must(WithCapacity(1000, 3), WithConcurrency())
+	if _, ok := f.(*concurrentBloomFilter); !ok {
+		t.Errorf("Expected concurrentBloomFilter, got %T", f)
+	}
+}
+
+func TestConcurrentBloomFilter_AddAndExists(t *testing.T) {
+	f := Must(WithAccuracy(0.01, 10_000), WithConcurrency())
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				item := []byte(fmt.Sprintf("%d-%d", g, i))
+				f.Add(item)
+				if !f.Exists(item) {
+					t.Errorf("Bloom Filter has false negative")
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if f.Count() != 8*500 {
+		t.Errorf("expected %v, got %v", 8*500, f.Count())
+	}
+}
+
+func TestConcurrentBloomFilter_UnionAndIntersectBothWays(t *testing.T) {
+	cf := WithCapacity(4096, 3)
+	a := Must(cf, WithConcurrency())
+	b := Must(cf, WithConcurrency())
+	a.Add([]byte("a"))
+	b.Add([]byte("b"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(4)
+		go func() { defer wg.Done(); _ = a.Union(b) }()
+		go func() { defer wg.Done(); _ = b.Union(a) }()
+		go func() { defer wg.Done(); _ = a.Intersect(a) }()
+		go func() { defer wg.Done(); a.Add([]byte("c")) }()
+	}
+	wg.Wait()
+
+	for _, item := range []string{"a", "b", "c"} {
+		if !a.Exists([]byte(item)) {
+			t.Errorf("expected %v exists", item)
+		}
+	}
+	if !b.Exists([]byte("a")) || !b.Exists([]byte("b")) {
+		t.Errorf("expected a and b exist")
+	}
+}
+
+func TestBloomFilter_MergesConcurrentFilterWhileItIsWritten(t *testing.T) {
+	cf := WithCapacity(4096, 3)
+	x := Must(cf)
+	c := Must(cf, WithConcurrency())
+	c.Add([]byte("c"))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.Add([]byte(fmt.Sprintf("item-%d", i)))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = x.Union(c)
+			_ = x.Intersect(c)
+			_ = x.CopyFrom(c)
+		}
+	}()
+	wg.Wait()
+
+	if err := x.Union(c); err != nil || !x.Exists([]byte("c")) {
+		t.Errorf("expected c exists, got %v", err)
+	}
+}
+
+func TestConcurrentBloomFilter_ForwardsErrors(t *testing.T) {
+	a := Must(WithCapacity(4096, 3), WithConcurrency())
+	b := Must(WithCapacity(2048, 3), WithConcurrency())
+
+	if err := a.Union(b); !errors.Is(err, ErrStorageDifference) {
+		t.Errorf("expected ErrStorageDifference, got %v", err)
+	}
+	if err := a.Intersect(nil); !errors.Is(err, ErrNilBloomFilter) {
+		t.Errorf("expected ErrNilBloomFilter, got %v", err)
+	}
+}
+
+func TestConcurrentBloomFilter_Clone(t *testing.T) {
+	f := Must(WithCapacity(4096, 3), WithConcurrency())
+	f.Add([]byte("a"))
+
+	r, err := f.Clone()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, ok := r.(*concurrentBloomFilter); !ok {
+		t.Errorf("expected concurrentBloomFilter, got %T", r)
+	}
+	if r == f || r.Storage() == f.Storage() {
+		t.Errorf("expected different instance")
+	}
+	if !r.Exists([]byte("a")) || r.Count() != 1 {
+		t.Errorf("expected cloned filter has the same data")
+	}
+	if r.Config() != f.Config() || !r.Hasher().Equals(f.Hasher()) {
+		t.Errorf("expected cloned filter has the same config and hasher")
+	}
+}
+
+func TestConcurrentBloomFilter_CopyFromBothWaysAndReset(t *testing.T) {
+	cf := WithCapacity(4096, 3)
+	a := Must(cf, WithConcurrency())
+	b := Must(cf, WithConcurrency())
+	a.Add([]byte("a"))
+	b.Add([]byte("b"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(4)
+		go func() { defer wg.Done(); _ = a.CopyFrom(b) }()
+		go func() { defer wg.Done(); _ = b.CopyFrom(a) }()
+		go func() { defer wg.Done(); _ = a.CopyFrom(a) }()
+		go func() { defer wg.Done(); b.Add([]byte("c")) }()
+	}
+	wg.Wait()
+
+	if err := a.CopyFrom(b); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if !a.Exists([]byte("c")) || a.AddCount() != b.AddCount() {
+		t.Errorf("expected a is a copy of b")
+	}
+
+	a.Reset()
+	if a.Exists([]byte("c")) || a.Count() != 0 || !b.Exists([]byte("c")) {
+		t.Errorf("expected only a is reset")
+	}
+	if err := a.CopyFrom(Must(WithCapacity(2048, 3))); !errors.Is(err, ErrStorageDifference) {
+		t.Errorf("expected ErrStorageDifference, got %v", err)
+	}
+}
+
+func TestConcurrentBloomFilter_MarshalAndUnmarshal(t *testing.T) {
+	f := Must(WithCapacity(4096, 3), WithConcurrency())
+	f.Add([]byte("a"))
+
+	data, err := Marshal(f)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	r, err := Unmarshal(data, WithConcurrency())
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, ok := r.(*concurrentBloomFilter); !ok {
+		t.Errorf("expected concurrentBloomFilter, got %T", r)
+	}
+	if !r.Exists([]byte("a")) {
+		t.Errorf("expected a exists")
+	}
+}
+
+func TestConcurrentBloomFilter_TestAndAdd(t *testing.T) {
+	f := Must(WithAccuracy(0.01, 10_000), WithConcurrency())
+
+	for i := 0; i < 100; i++ {
+		item := []byte(fmt.Sprintf("%d", i))
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		added := 0
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if !f.TestAndAdd(item) {
+					mu.Lock()
+					added++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if added != 1 {
+			t.Errorf("expected exactly one goroutine sees %s as new, got %v", item, added)
+		}
+	}
+}
@@ -34,6 +34,11 @@ type config struct {
 	e               float64
 	requestedE      float64
 	storageCapacity uint32
+	keySize         byte
+
+	// multiplyShift is set for the Config of a filter which uses
+	// IndexMappingMultiplyShift, its hasher makes 32-bit keys.
+	multiplyShift bool
 }
 
 func (c config) NumberOfHashFunctions() byte {
@@ -45,6 +50,12 @@ func (c config) StorageCapacity() uint32 {
 }
 
 func (c config) KeySize() byte {
+	if c.multiplyShift {
+		return 32
+	}
+	if c.keySize > 0 {
+		return c.keySize
+	}
 	return calcKeyMinSizeFromCapacity(c.storageCapacity)
 }
 
@@ -0,0 +1,534 @@
+package bf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const durableSnapshotFile = "snapshot.bf"
+const durableLogFile = "wal.log"
+const durableLogHeaderSize = 12
+
+var durableLogMagic = [4]byte{'G', 'B', 'F', 'L'}
+
+type DurableOption struct {
+	syncEvery    int
+	compactEvery int
+	options      []OptionFunc
+}
+
+type DurableOptionFunc func(option *DurableOption)
+
+/*
+DurableBloomFilter persists a BloomFilter in a directory. Every Add is appended
+to a write-ahead log of storage indexes before applied, the log is compacted
+into a full snapshot by Compact, Union, Intersect or automatically
+WithCompactEvery. OpenDurable recovers the filter by loading the snapshot and
+replaying the log, a partially written record at the end of the log is
+discarded. Only the built-in hashers WithSHA and WithFNV are supported.
+
+Methods which cannot return an error record it, the last error could be
+retrieved by Err. DurableBloomFilter is safe for concurrent use.
+*/
+type DurableBloomFilter struct {
+	mu       sync.RWMutex
+	dir      string
+	option   DurableOption
+	filter   *bloomFilter
+	log      *os.File
+	sequence uint64
+	records  int
+	unsynced int
+	err      error
+}
+
+var _ BloomFilter = (*DurableBloomFilter)(nil)
+
+/*
+OpenDurable opens or creates a DurableBloomFilter in the given directory. The
+Config and filter options given by WithFilterOptions are used to create a new
+filter if there is no snapshot, otherwise the snapshot must have the same
+Config. By default, the log is synced after every Add WithSyncAlways.
+*/
+func OpenDurable(dir string, config Config, opts ...DurableOptionFunc) (*DurableBloomFilter, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+
+	o := DurableOption{syncEvery: 1}
+	for _, opt := range opts {
+		if opt == nil {
+			return nil, ErrNilOptionFunc
+		}
+		opt(&o)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	d := &DurableBloomFilter{dir: dir, option: o}
+	if err := d.loadSnapshot(config); err != nil {
+		return nil, err
+	}
+	if err := d.replayLog(); err != nil {
+		return nil, err
+	}
+
+	// a snapshot is written right away so the IndexMapping of a new filter is
+	// known when it is opened again
+	if d.sequence == 0 {
+		if err := d.compact(); err != nil {
+			_ = d.log.Close()
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// WithSyncAlways syncs the log to disk after every Add, this is the default.
+func WithSyncAlways() DurableOptionFunc {
+	return func(o *DurableOption) {
+		o.syncEvery = 1
+	}
+}
+
+// WithSyncEvery syncs the log to disk after every n Add.
+func WithSyncEvery(n int) DurableOptionFunc {
+	return func(o *DurableOption) {
+		o.syncEvery = n
+	}
+}
+
+// WithSyncNever leaves syncing the log to the operating system, it is still
+// synced by Sync, Compact and Close.
+func WithSyncNever() DurableOptionFunc {
+	return func(o *DurableOption) {
+		o.syncEvery = 0
+	}
+}
+
+// WithCompactEvery compacts the log into a snapshot after every n Add, 0
+// disables automatic compaction.
+func WithCompactEvery(n int) DurableOptionFunc {
+	return func(o *DurableOption) {
+		o.compactEvery = n
+	}
+}
+
+// WithFilterOptions sets options used to create or load the filter.
+func WithFilterOptions(opts ...OptionFunc) DurableOptionFunc {
+	return func(o *DurableOption) {
+		o.options = opts
+	}
+}
+
+func (d *DurableBloomFilter) Add(item []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.add(d.filter.indexes(item))
+}
+
+func (d *DurableBloomFilter) TestAndAdd(item []byte) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.add(d.filter.indexes(item))
+}
+
+func (d *DurableBloomFilter) Keys(item []byte) KeyHandle {
+	return d.filter.Keys(item)
+}
+
+// AddKeys appends indexes of the handle to the log like Add.
+func (d *DurableBloomFilter) AddKeys(h KeyHandle) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	indexes, err := d.filter.indexesOf(h)
+	if err != nil {
+		return err
+	}
+	d.add(indexes)
+	return nil
+}
+
+func (d *DurableBloomFilter) ExistsKeys(h KeyHandle) (bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.filter.ExistsKeys(h)
+}
+
+func (d *DurableBloomFilter) Exists(item []byte) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.filter.Exists(item)
+}
+
+func (d *DurableBloomFilter) Count() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.filter.Count()
+}
+
+func (d *DurableBloomFilter) AddCount() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.filter.AddCount()
+}
+
+func (d *DurableBloomFilter) DistinctCount() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.filter.DistinctCount()
+}
+
+func (d *DurableBloomFilter) Storage() Storage {
+	return d.filter.Storage()
+}
+
+func (d *DurableBloomFilter) Hasher() Hasher {
+	return d.filter.Hasher()
+}
+
+func (d *DurableBloomFilter) Config() Config {
+	return d.filter.Config()
+}
+
+// Intersect applies Intersect to the filter then compacts the log.
+func (d *DurableBloomFilter) Intersect(other ReadOnlyFilter) error {
+	other = d.stable(other)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.filter.Intersect(other); err != nil {
+		return err
+	}
+	return d.compact()
+}
+
+// Union applies Union to the filter then compacts the log.
+func (d *DurableBloomFilter) Union(other ReadOnlyFilter) error {
+	other = d.stable(other)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.filter.Union(other); err != nil {
+		return err
+	}
+	return d.compact()
+}
+
+// Clone returns an in-memory copy of the filter.
+func (d *DurableBloomFilter) Clone() (BloomFilter, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.filter.Clone()
+}
+
+// Reset clears the filter then compacts the log, the error is recorded.
+func (d *DurableBloomFilter) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.filter.Reset()
+	d.record(d.compact())
+}
+
+// CopyFrom applies CopyFrom to the filter then compacts the log.
+func (d *DurableBloomFilter) CopyFrom(other ReadOnlyFilter) error {
+	if other == ReadOnlyFilter(d) {
+		return nil
+	}
+	other = d.stable(other)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.filter.CopyFrom(other); err != nil {
+		return err
+	}
+	return d.compact()
+}
+
+// stable returns a filter which could be read while holding the lock of d, see
+// concurrentBloomFilter.stable.
+func (d *DurableBloomFilter) stable(other ReadOnlyFilter) ReadOnlyFilter {
+	if other == ReadOnlyFilter(d) {
+		return d.filter
+	}
+	return stable(other)
+}
+
+// Compact writes a snapshot of the filter and starts a new empty log.
+func (d *DurableBloomFilter) Compact() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.compact()
+}
+
+// Sync commits the log to disk.
+func (d *DurableBloomFilter) Sync() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.unsynced = 0
+	return d.log.Sync()
+}
+
+// Close syncs and closes the log, the filter must not be used afterwards.
+func (d *DurableBloomFilter) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	err := d.log.Sync()
+	if cErr := d.log.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+// Err returns the last error of methods which cannot return an error.
+func (d *DurableBloomFilter) Err() error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.err
+}
+
+func (d *DurableBloomFilter) record(err error) {
+	if err != nil {
+		d.err = err
+	}
+}
+
+func (d *DurableBloomFilter) add(indexes []uint32) bool {
+	d.record(d.appendLog(indexes))
+	existed := d.apply(indexes)
+
+	if d.option.compactEvery > 0 && d.records >= d.option.compactEvery {
+		d.record(d.compact())
+	}
+	return existed
+}
+
+func (d *DurableBloomFilter) apply(indexes []uint32) bool {
+	existed := true
+	for _, index := range indexes {
+		if !d.filter.storage.Get(index) {
+			existed = false
+			d.filter.storage.Set(index)
+		}
+	}
+	d.filter.inserted(existed)
+	return existed
+}
+
+func (d *DurableBloomFilter) appendLog(indexes []uint32) error {
+	buf := make([]byte, 1+4*len(indexes)+4)
+	buf[0] = byte(len(indexes))
+	for i, index := range indexes {
+		binary.LittleEndian.PutUint32(buf[1+4*i:], index)
+	}
+	binary.LittleEndian.PutUint32(buf[len(buf)-4:], crc32.ChecksumIEEE(buf[:len(buf)-4]))
+
+	if _, err := d.log.Write(buf); err != nil {
+		return err
+	}
+	d.records++
+	d.unsynced++
+
+	if d.option.syncEvery > 0 && d.unsynced >= d.option.syncEvery {
+		d.unsynced = 0
+		return d.log.Sync()
+	}
+	return nil
+}
+
+func (d *DurableBloomFilter) loadSnapshot(config Config) error {
+	data, err := os.ReadFile(filepath.Join(d.dir, durableSnapshotFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return d.create(config)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(data) < 8 {
+		return ErrInvalidSerializedData
+	}
+	f, err := Unmarshal(data[8:], d.option.options...)
+	if err != nil {
+		return err
+	}
+
+	cfg := f.Config()
+	b := innerBloomFilter(f).(*bloomFilter)
+	if cfg.StorageCapacity() != config.StorageCapacity() ||
+		cfg.NumberOfHashFunctions() != config.NumberOfHashFunctions() ||
+		cfg.KeySize() != configWithMapping(config, b.option.mapping).KeySize() {
+		return ErrSnapshotConfigDifference
+	}
+
+	d.filter = b
+	d.sequence = binary.LittleEndian.Uint64(data)
+	return nil
+}
+
+// create makes a new filter when there is no snapshot. A log without a snapshot
+// was written before the IndexMapping was kept in snapshots, so its filter uses
+// IndexMappingModulo.
+func (d *DurableBloomFilter) create(config Config) error {
+	opts := d.option.options
+	_, err := os.Stat(filepath.Join(d.dir, durableLogFile))
+	if err == nil {
+		opts = append(opts[:len(opts):len(opts)], WithIndexMapping(IndexMappingModulo))
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	f, err := New(config, opts...)
+	if err != nil {
+		return err
+	}
+	d.filter = innerBloomFilter(f).(*bloomFilter)
+	return nil
+}
+
+func (d *DurableBloomFilter) replayLog() error {
+	name := filepath.Join(d.dir, durableLogFile)
+	data, err := os.ReadFile(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if len(data) < durableLogHeaderSize {
+		return d.resetLog()
+	}
+	if !bytes.Equal(data[:4], durableLogMagic[:]) {
+		return ErrInvalidLog
+	}
+	if binary.LittleEndian.Uint64(data[4:]) != d.sequence {
+		// the log was written before the current snapshot was taken
+		return d.resetLog()
+	}
+
+	offset := durableLogHeaderSize
+	for offset < len(data) {
+		n := int(data[offset])
+		end := offset + 1 + 4*n + 4
+		if end > len(data) {
+			break
+		}
+		if crc32.ChecksumIEEE(data[offset:end-4]) != binary.LittleEndian.Uint32(data[end-4:]) {
+			break
+		}
+
+		indexes := make([]uint32, n)
+		for i := range indexes {
+			indexes[i] = binary.LittleEndian.Uint32(data[offset+1+4*i:])
+			if indexes[i] >= d.filter.storage.Capacity() {
+				return ErrInvalidLog
+			}
+		}
+		d.apply(indexes)
+		d.records++
+		offset = end
+	}
+
+	if offset < len(data) {
+		if err = os.Truncate(name, int64(offset)); err != nil {
+			return err
+		}
+	}
+
+	d.log, err = os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0o644)
+	return err
+}
+
+func (d *DurableBloomFilter) resetLog() error {
+	header := make([]byte, durableLogHeaderSize)
+	copy(header, durableLogMagic[:])
+	binary.LittleEndian.PutUint64(header[4:], d.sequence)
+
+	name := filepath.Join(d.dir, durableLogFile)
+	if err := d.writeFileAtomic(name, header); err != nil {
+		return err
+	}
+
+	if d.log != nil {
+		_ = d.log.Close()
+	}
+	log, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	d.log = log
+	d.records = 0
+	d.unsynced = 0
+	return nil
+}
+
+func (d *DurableBloomFilter) compact() error {
+	data, err := Marshal(d.filter)
+	if err != nil {
+		return err
+	}
+
+	snapshot := make([]byte, 8, 8+len(data))
+	binary.LittleEndian.PutUint64(snapshot, d.sequence+1)
+	snapshot = append(snapshot, data...)
+	if err = d.writeFileAtomic(filepath.Join(d.dir, durableSnapshotFile), snapshot); err != nil {
+		return err
+	}
+
+	d.sequence++
+	return d.resetLog()
+}
+
+func (d *DurableBloomFilter) writeFileAtomic(name string, data []byte) error {
+	tmp := name + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp, name); err != nil {
+		return err
+	}
+
+	dir, err := os.Open(d.dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,391 @@
+package bf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openDurableForTest(t *testing.T, dir string, opts ...DurableOptionFunc) *DurableBloomFilter {
+	d, err := OpenDurable(dir, WithCapacity(4096, 3), opts...)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	return d
+}
+
+func durableLogSizeForTest(t *testing.T, dir string) int64 {
+	info, err := os.Stat(filepath.Join(dir, durableLogFile))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	return info.Size()
+}
+
+func assertDurableContains(t *testing.T, d *DurableBloomFilter, n int) {
+	for i := 0; i < n; i++ {
+		if !d.Exists([]byte(fmt.Sprintf("item-%d", i))) {
+			t.Errorf("expected item-%d exists", i)
+		}
+	}
+	if d.Count() != n {
+		t.Errorf("expected count %v, got %v", n, d.Count())
+	}
+}
+
+func TestDurableOptions(t *testing.T) {
+	o := &DurableOption{}
+	WithSyncEvery(10)(o)
+	WithCompactEvery(100)(o)
+	WithFilterOptions(WithFNV())(o)
+	if o.syncEvery != 10 || o.compactEvery != 100 || len(o.options) != 1 {
+		t.Errorf("unexpected option %+v", o)
+	}
+
+	WithSyncAlways()(o)
+	if o.syncEvery != 1 {
+		t.Errorf("expected 1, got %v", o.syncEvery)
+	}
+	WithSyncNever()(o)
+	if o.syncEvery != 0 {
+		t.Errorf("expected 0, got %v", o.syncEvery)
+	}
+}
+
+func TestOpenDurable_ReturnsErrIfArgumentsAreInvalid(t *testing.T) {
+	if _, err := OpenDurable(t.TempDir(), nil); !errors.Is(err, ErrNilConfig) {
+		t.Errorf("expected ErrNilConfig, got %v", err)
+	}
+	if _, err := OpenDurable(t.TempDir(), WithCapacity(100, 2), nil); !errors.Is(err, ErrNilOptionFunc) {
+		t.Errorf("expected ErrNilOptionFunc, got %v", err)
+	}
+}
+
+func TestDurableBloomFilter_RecoversFromLog(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir, WithFilterOptions(WithFNV()))
+	for i := 0; i < 10; i++ {
+		d.Add([]byte(fmt.Sprintf("item-%d", i)))
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	r := openDurableForTest(t, dir, WithFilterOptions(WithFNV()))
+	defer r.Close()
+	assertDurableContains(t, r, 10)
+	if _, ok := r.Hasher().(*fnvHasher); !ok {
+		t.Errorf("expected fnvHasher, got %T", r.Hasher())
+	}
+}
+
+func TestDurableBloomFilter_TestAndAdd(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir)
+	for i := 0; i < 10; i++ {
+		if d.TestAndAdd([]byte(fmt.Sprintf("item-%d", i))) {
+			t.Errorf("expected item-%d is new", i)
+		}
+	}
+	_ = d.Close()
+
+	r := openDurableForTest(t, dir)
+	defer r.Close()
+	assertDurableContains(t, r, 10)
+	if !r.TestAndAdd([]byte("item-0")) {
+		t.Errorf("expected item-0 existed")
+	}
+}
+
+func TestDurableBloomFilter_DistinctCount(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir, WithFilterOptions(WithDistinctCount()))
+	d.Add([]byte("a"))
+	_ = d.Compact()
+	d.Add([]byte("a"))
+	d.Add([]byte("b"))
+	_ = d.Close()
+
+	r := openDurableForTest(t, dir, WithFilterOptions(WithDistinctCount()))
+	defer r.Close()
+	if r.AddCount() != 3 || r.DistinctCount() != 2 || r.Count() != 2 {
+		t.Errorf("expected counters 3, 2, 2, got %v, %v, %v", r.AddCount(), r.DistinctCount(), r.Count())
+	}
+}
+
+func TestDurableBloomFilter_Compact(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir, WithSyncNever())
+	for i := 0; i < 5; i++ {
+		d.Add([]byte(fmt.Sprintf("item-%d", i)))
+	}
+	if err := d.Compact(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if durableLogSizeForTest(t, dir) != durableLogHeaderSize {
+		t.Errorf("expected log is empty after Compact")
+	}
+
+	for i := 5; i < 8; i++ {
+		d.Add([]byte(fmt.Sprintf("item-%d", i)))
+	}
+	_ = d.Close()
+
+	if durableLogSizeForTest(t, dir) != durableLogHeaderSize+3*(1+3*4+4) {
+		t.Errorf("expected log contains 3 records")
+	}
+	r := openDurableForTest(t, dir)
+	defer r.Close()
+	assertDurableContains(t, r, 8)
+}
+
+func TestDurableBloomFilter_CompactEvery(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir, WithCompactEvery(3), WithSyncEvery(2))
+	for i := 0; i < 7; i++ {
+		d.Add([]byte(fmt.Sprintf("item-%d", i)))
+	}
+	_ = d.Close()
+
+	if d.Err() != nil {
+		t.Errorf("expected nil, got %v", d.Err())
+	}
+	if durableLogSizeForTest(t, dir) != durableLogHeaderSize+(1+3*4+4) {
+		t.Errorf("expected log contains 1 record")
+	}
+	r := openDurableForTest(t, dir)
+	defer r.Close()
+	assertDurableContains(t, r, 7)
+}
+
+func TestDurableBloomFilter_RecoversFromLogTruncatedMidRecord(t *testing.T) {
+	const recordSize = 1 + 3*4 + 4
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir)
+	for i := 0; i < 3; i++ {
+		d.Add([]byte(fmt.Sprintf("item-%d", i)))
+	}
+	_ = d.Close()
+
+	name := filepath.Join(dir, durableLogFile)
+	full, _ := os.ReadFile(name)
+
+	for size := durableLogHeaderSize; size < len(full); size++ {
+		if err := os.WriteFile(name, full[:size], 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		records := (size - durableLogHeaderSize) / recordSize
+		r := openDurableForTest(t, dir)
+		if r.Count() != records {
+			t.Errorf("size %d: expected %d records, got %d", size, records, r.Count())
+		}
+		if durableLogSizeForTest(t, dir) != int64(durableLogHeaderSize+records*recordSize) {
+			t.Errorf("size %d: expected partial record is truncated", size)
+		}
+
+		r.Add([]byte("after-crash"))
+		_ = r.Close()
+
+		r = openDurableForTest(t, dir)
+		if !r.Exists([]byte("after-crash")) || r.Count() != records+1 {
+			t.Errorf("size %d: expected record after crash is replayed", size)
+		}
+		_ = r.Close()
+	}
+}
+
+func TestDurableBloomFilter_DiscardsCorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir)
+	d.Add([]byte("item-0"))
+	d.Add([]byte("item-1"))
+	_ = d.Close()
+
+	name := filepath.Join(dir, durableLogFile)
+	data, _ := os.ReadFile(name)
+	data[len(data)-5] ^= 0xff
+	_ = os.WriteFile(name, data, 0o644)
+
+	r := openDurableForTest(t, dir)
+	defer r.Close()
+	assertDurableContains(t, r, 1)
+}
+
+func TestDurableBloomFilter_IgnoresLogWrittenBeforeSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir)
+	d.Add([]byte("item-0"))
+	d.Add([]byte("item-1"))
+
+	name := filepath.Join(dir, durableLogFile)
+	stale, _ := os.ReadFile(name)
+	_ = d.Compact()
+	_ = d.Close()
+
+	// crash after the snapshot is written but before the log is reset
+	_ = os.WriteFile(name, stale, 0o644)
+
+	r := openDurableForTest(t, dir)
+	defer r.Close()
+	assertDurableContains(t, r, 2)
+}
+
+func TestDurableBloomFilter_UnionAndIntersectCompactTheLog(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir)
+	d.Add([]byte("a"))
+
+	other := Must(WithCapacity(4096, 3))
+	other.Add([]byte("b"))
+	if err := d.Union(other); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if durableLogSizeForTest(t, dir) != durableLogHeaderSize {
+		t.Errorf("expected log is empty after Union")
+	}
+	if err := d.Intersect(Must(WithCapacity(1024, 3))); !errors.Is(err, ErrStorageDifference) {
+		t.Errorf("expected ErrStorageDifference, got %v", err)
+	}
+	_ = d.Close()
+
+	r := openDurableForTest(t, dir)
+	defer r.Close()
+	if !r.Exists([]byte("a")) || !r.Exists([]byte("b")) || r.Count() != -1 {
+		t.Errorf("expected union is persisted")
+	}
+
+	cloned, err := r.Clone()
+	if err != nil || !cloned.Exists([]byte("b")) {
+		t.Errorf("expected cloned filter contains b, got %v", err)
+	}
+	if r.Storage() == nil || r.Config() == nil {
+		t.Errorf("expected storage and config")
+	}
+}
+
+func TestDurableBloomFilter_MergesDurableFilterWhileItIsWritten(t *testing.T) {
+	a := openDurableForTest(t, t.TempDir())
+	b := openDurableForTest(t, t.TempDir())
+	defer a.Close()
+	defer b.Close()
+	b.Add([]byte("b"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			b.Add([]byte(fmt.Sprintf("item-%d", i)))
+			_ = b.Union(a)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		_ = a.Union(b)
+		_ = a.Intersect(b)
+		_ = a.CopyFrom(b)
+		_ = a.Union(a)
+	}
+	<-done
+
+	if err := a.Union(b); err != nil || !a.Exists([]byte("b")) {
+		t.Errorf("expected b exists, got %v", err)
+	}
+}
+
+func TestDurableBloomFilter_ResetAndCopyFromCompactTheLog(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir)
+	d.Add([]byte("a"))
+
+	other := Must(WithCapacity(4096, 3))
+	other.Add([]byte("b"))
+	if err := d.CopyFrom(other); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if err := d.CopyFrom(d); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if durableLogSizeForTest(t, dir) != durableLogHeaderSize {
+		t.Errorf("expected log is empty after CopyFrom")
+	}
+	if err := d.CopyFrom(Must(WithCapacity(1024, 3))); !errors.Is(err, ErrStorageDifference) {
+		t.Errorf("expected ErrStorageDifference, got %v", err)
+	}
+	_ = d.Close()
+
+	r := openDurableForTest(t, dir)
+	if r.Exists([]byte("a")) || !r.Exists([]byte("b")) || r.Count() != 1 {
+		t.Errorf("expected copy is persisted")
+	}
+
+	r.Reset()
+	r.Add([]byte("c"))
+	_ = r.Close()
+
+	r = openDurableForTest(t, dir)
+	defer r.Close()
+	if r.Exists([]byte("b")) || !r.Exists([]byte("c")) || r.Count() != 1 || r.Err() != nil {
+		t.Errorf("expected reset is persisted, got %v", r.Err())
+	}
+}
+
+func TestOpenDurable_ReturnsErrIfSnapshotConfigIsDifferent(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir)
+	_ = d.Compact()
+	_ = d.Close()
+
+	_, err := OpenDurable(dir, WithCapacity(2048, 3))
+	if !errors.Is(err, ErrSnapshotConfigDifference) {
+		t.Errorf("expected ErrSnapshotConfigDifference, got %v", err)
+	}
+}
+
+func TestOpenDurable_ReturnsErrIfLogIsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, durableLogFile), []byte("not a log file"), 0o644)
+
+	_, err := OpenDurable(dir, WithCapacity(4096, 3))
+	if !errors.Is(err, ErrInvalidLog) {
+		t.Errorf("expected ErrInvalidLog, got %v", err)
+	}
+}
+
+func TestDurableBloomFilter_KeepsIndexMapping(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir, WithFilterOptions(WithIndexMapping(IndexMappingModulo)))
+	d.Add([]byte("item-0"))
+	_ = d.Close()
+
+	r := openDurableForTest(t, dir)
+	if r.filter.option.mapping != IndexMappingModulo {
+		t.Errorf("expected %v, got %v", IndexMappingModulo, r.filter.option.mapping)
+	}
+	assertDurableContains(t, r, 1)
+	_ = r.Close()
+}
+
+func TestDurableBloomFilter_LogWithoutSnapshotUsesModulo(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir, WithFilterOptions(WithIndexMapping(IndexMappingModulo)))
+	d.Add([]byte("item-0"))
+	d.Add([]byte("item-1"))
+	_ = d.Close()
+
+	// a directory written before snapshots kept the IndexMapping has only a log
+	_ = os.Remove(filepath.Join(dir, durableSnapshotFile))
+	name := filepath.Join(dir, durableLogFile)
+	data, _ := os.ReadFile(name)
+	copy(data[4:12], make([]byte, 8))
+	_ = os.WriteFile(name, data, 0o644)
+
+	r := openDurableForTest(t, dir)
+	assertDurableContains(t, r, 2)
+	_ = r.Close()
+
+	r = openDurableForTest(t, dir)
+	defer r.Close()
+	assertDurableContains(t, r, 2)
+}
@@ -0,0 +1,233 @@
+package bf
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+type Encoding byte
+
+const (
+	// EncodingRaw stores every bit of the Storage.
+	EncodingRaw Encoding = iota
+	// EncodingRunLength stores runs of zero bytes as their length.
+	EncodingRunLength
+	// EncodingEliasFano stores positions of set bits by Elias-Fano coding.
+	EncodingEliasFano
+	// EncodingAuto picks EncodingEliasFano or EncodingRaw by the fill ratio.
+	EncodingAuto Encoding = math.MaxUint8
+)
+
+type MarshalOption struct {
+	encoding Encoding
+	mapping  *IndexMapping
+}
+
+type MarshalOptionFunc func(option *MarshalOption)
+
+// WithEncoding selects how Storage data is encoded by Marshal, default is
+// EncodingAuto.
+func WithEncoding(e Encoding) MarshalOptionFunc {
+	return func(o *MarshalOption) {
+		o.encoding = e
+	}
+}
+
+// WithMarshalIndexMapping states the IndexMapping of a filter which is not
+// built-in, it is ignored for built-in filters.
+func WithMarshalIndexMapping(m IndexMapping) MarshalOptionFunc {
+	return func(o *MarshalOption) {
+		o.mapping = &m
+	}
+}
+
+func chooseEncoding(data []byte, capacity uint32) Encoding {
+	var n uint64
+	for _, b := range data {
+		n += uint64(bits.OnesCount8(b))
+	}
+
+	if eliasFanoSizeInBytes(n, uint64(capacity)) < uint64(len(data)) {
+		return EncodingEliasFano
+	}
+	return EncodingRaw
+}
+
+func encodePayload(e Encoding, data []byte, capacity uint32) ([]byte, error) {
+	switch e {
+	case EncodingRaw:
+		return data, nil
+	case EncodingRunLength:
+		return encodeRunLength(data), nil
+	case EncodingEliasFano:
+		return encodeEliasFano(data, capacity), nil
+	}
+	return nil, ErrUnsupportedEncoding
+}
+
+func decodePayload(e Encoding, payload []byte, capacity uint32) ([]byte, error) {
+	size := int(storageSizeInBytes(capacity))
+	switch e {
+	case EncodingRaw:
+		if len(payload) != size {
+			return nil, ErrInvalidSerializedData
+		}
+		return payload, nil
+	case EncodingRunLength:
+		return decodeRunLength(payload, size)
+	case EncodingEliasFano:
+		return decodeEliasFano(payload, capacity)
+	}
+	return nil, ErrUnsupportedEncoding
+}
+
+// encodeRunLength writes pairs of uvarint number of zero bytes, uvarint number
+// of literal bytes followed by the literal bytes.
+func encodeRunLength(data []byte) []byte {
+	var result []byte
+	for i := 0; i < len(data); {
+		zeros := i
+		for i < len(data) && data[i] == 0 {
+			i++
+		}
+		literals := i
+		for i < len(data) && data[i] != 0 {
+			i++
+		}
+
+		result = binary.AppendUvarint(result, uint64(literals-zeros))
+		result = binary.AppendUvarint(result, uint64(i-literals))
+		result = append(result, data[literals:i]...)
+	}
+	return result
+}
+
+func decodeRunLength(payload []byte, size int) ([]byte, error) {
+	result := make([]byte, 0, size)
+	for len(payload) > 0 {
+		zeros, n := binary.Uvarint(payload)
+		if n <= 0 || zeros > uint64(size-len(result)) {
+			return nil, ErrInvalidSerializedData
+		}
+		payload = payload[n:]
+		result = result[:len(result)+int(zeros)]
+
+		literals, n := binary.Uvarint(payload)
+		if n <= 0 || literals > uint64(size-len(result)) || literals > uint64(len(payload)-n) {
+			return nil, ErrInvalidSerializedData
+		}
+		result = append(result, payload[n:n+int(literals)]...)
+		payload = payload[n+int(literals):]
+	}
+
+	if len(result) != size {
+		return nil, ErrInvalidSerializedData
+	}
+	return result, nil
+}
+
+func eliasFanoLowBits(n, u uint64) uint {
+	if n == 0 {
+		return uint(bits.Len64(u))
+	}
+	if u <= n {
+		return 0
+	}
+	return uint(bits.Len64(u/n) - 1)
+}
+
+func eliasFanoSizes(n, u uint64, l uint) (uint64, uint64) {
+	return (n*uint64(l) + 7) / 8, (n + (u >> l) + 1 + 7) / 8
+}
+
+func eliasFanoSizeInBytes(n, u uint64) uint64 {
+	lowSize, highSize := eliasFanoSizes(n, u, eliasFanoLowBits(n, u))
+	return uint64(len(binary.AppendUvarint(nil, n))) + 1 + lowSize + highSize
+}
+
+// encodeEliasFano writes uvarint number of set bits n, number of low bits l,
+// the low l bits of every position then the high bits of every position in
+// unary code.
+func encodeEliasFano(data []byte, capacity uint32) []byte {
+	var n uint64
+	for _, b := range data {
+		n += uint64(bits.OnesCount8(b))
+	}
+
+	u := uint64(capacity)
+	l := eliasFanoLowBits(n, u)
+	result := binary.AppendUvarint(nil, n)
+	result = append(result, byte(l))
+
+	lowSize, highSize := eliasFanoSizes(n, u, l)
+	low, high := make([]byte, lowSize), make([]byte, highSize)
+	var i uint64
+	for j, b := range data {
+		for b != 0 {
+			pos := uint64(j*8 + bits.TrailingZeros8(b))
+			b &= b - 1
+
+			for k := uint(0); k < l; k++ {
+				if pos&(1<<k) > 0 {
+					bit := i*uint64(l) + uint64(k)
+					low[bit/8] |= 1 << (bit % 8)
+				}
+			}
+			bit := (pos >> l) + i
+			high[bit/8] |= 1 << (bit % 8)
+			i++
+		}
+	}
+
+	result = append(result, low...)
+	return append(result, high...)
+}
+
+func decodeEliasFano(payload []byte, capacity uint32) ([]byte, error) {
+	u := uint64(capacity)
+	n, m := binary.Uvarint(payload)
+	if m <= 0 || n > u || len(payload) < m+1 {
+		return nil, ErrInvalidSerializedData
+	}
+
+	l := uint(payload[m])
+	if l != eliasFanoLowBits(n, u) {
+		return nil, ErrInvalidSerializedData
+	}
+	payload = payload[m+1:]
+
+	lowSize, highSize := eliasFanoSizes(n, u, l)
+	if uint64(len(payload)) != lowSize+highSize {
+		return nil, ErrInvalidSerializedData
+	}
+	low, high := payload[:lowSize], payload[lowSize:]
+
+	result := make([]byte, storageSizeInBytes(capacity))
+	var i, last uint64
+	for bit := uint64(0); bit < highSize*8 && i < n; bit++ {
+		if high[bit/8]&(1<<(bit%8)) == 0 {
+			continue
+		}
+
+		pos := (bit - i) << l
+		for k := uint(0); k < l; k++ {
+			b := i*uint64(l) + uint64(k)
+			if low[b/8]&(1<<(b%8)) > 0 {
+				pos |= 1 << k
+			}
+		}
+		if pos >= u || (i > 0 && pos <= last) {
+			return nil, ErrInvalidSerializedData
+		}
+
+		result[pos/8] |= 1 << (pos % 8)
+		last = pos
+		i++
+	}
+
+	if i != n {
+		return nil, ErrInvalidSerializedData
+	}
+	return result, nil
+}
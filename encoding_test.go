@@ -0,0 +1,154 @@
+package bf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func newBitsetWithFillRatio(capacity uint32, ratio float64, seed int64) *bitset {
+	s, _ := memoryStorageFactory{}.Make(capacity)
+	b := s.(*bitset)
+	r := rand.New(rand.NewSource(seed))
+	for i := uint32(0); i < capacity; i++ {
+		if r.Float64() < ratio {
+			b.Set(i)
+		}
+	}
+	return b
+}
+
+func TestWithEncoding(t *testing.T) {
+	o := &MarshalOption{}
+	WithEncoding(EncodingRunLength)(o)
+	if o.encoding != EncodingRunLength {
+		t.Errorf("expected EncodingRunLength, got %v", o.encoding)
+	}
+}
+
+func TestEncoding_RoundTrip(t *testing.T) {
+	capacities := []uint32{1, 7, 8, 63, 64, 65, 1000, 65_536, 100_003}
+	ratios := []float64{0, 0.0001, 0.01, 0.1, 0.5, 0.9, 1}
+	encodings := []Encoding{EncodingRaw, EncodingRunLength, EncodingEliasFano}
+
+	for _, capacity := range capacities {
+		for _, ratio := range ratios {
+			b := newBitsetWithFillRatio(capacity, ratio, int64(capacity))
+			data := b.exportBytes()
+
+			for _, e := range encodings {
+				t.Run(fmt.Sprintf("capacity=%d ratio=%v encoding=%d", capacity, ratio, e), func(t *testing.T) {
+					payload, err := encodePayload(e, data, capacity)
+					if err != nil {
+						t.Fatalf("expected nil, got %v", err)
+					}
+
+					decoded, err := decodePayload(e, payload, capacity)
+					if err != nil {
+						t.Fatalf("expected nil, got %v", err)
+					}
+
+					r := newBitset(uint32(len(b.data)), capacity)
+					r.importBytes(decoded)
+					if !isArrayEquals(b.data, r.data) {
+						t.Errorf("expected %v, got %v", b.data, r.data)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestEncoding_ChooseByFillRatio(t *testing.T) {
+	cases := []struct {
+		ratio    float64
+		expected Encoding
+	}{
+		{ratio: 0, expected: EncodingEliasFano},
+		{ratio: 0.001, expected: EncodingEliasFano},
+		{ratio: 0.05, expected: EncodingEliasFano},
+		{ratio: 0.3, expected: EncodingRaw},
+		{ratio: 0.5, expected: EncodingRaw},
+	}
+
+	for _, tc := range cases {
+		b := newBitsetWithFillRatio(100_000, tc.ratio, 1)
+		result := chooseEncoding(b.exportBytes(), b.capacity)
+		if result != tc.expected {
+			t.Errorf("ratio %v: expected %v, got %v", tc.ratio, tc.expected, result)
+		}
+	}
+}
+
+func TestMarshal_CompressesSparseFilter(t *testing.T) {
+	f := Must(WithAccuracy(0.001, 100_000))
+	for i := 0; i < 100; i++ {
+		f.Add([]byte(fmt.Sprintf("%d", i)))
+	}
+
+	raw, _ := Marshal(f, WithEncoding(EncodingRaw))
+	auto, _ := Marshal(f)
+	rle, _ := Marshal(f, WithEncoding(EncodingRunLength))
+	if len(auto)*50 > len(raw) || len(rle)*20 > len(raw) {
+		t.Errorf("expected sparse filter is compressed, raw %d, auto %d, run-length %d", len(raw), len(auto), len(rle))
+	}
+
+	for _, data := range [][]byte{raw, auto, rle} {
+		r, err := Unmarshal(data)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if !isArrayEquals(f.Storage().(*bitset).data, r.Storage().(*bitset).data) {
+			t.Errorf("expected the same data after Unmarshal")
+		}
+	}
+}
+
+func TestMarshal_ReturnsErrIfEncodingIsNotSupported(t *testing.T) {
+	f := Must(WithCapacity(100, 2))
+	if _, err := Marshal(f, WithEncoding(Encoding(99))); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("expected ErrUnsupportedEncoding, got %v", err)
+	}
+	if _, err := Marshal(f, nil); !errors.Is(err, ErrNilOptionFunc) {
+		t.Errorf("expected ErrNilOptionFunc, got %v", err)
+	}
+
+	data, _ := Marshal(f)
+	data[6] = 99
+	if _, err := Unmarshal(data); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("expected ErrUnsupportedEncoding, got %v", err)
+	}
+}
+
+func TestEncoding_DecodeReturnsErrIfPayloadIsInvalid(t *testing.T) {
+	const capacity = 1000
+	b := newBitsetWithFillRatio(capacity, 0.01, 1)
+	data := b.exportBytes()
+
+	for _, e := range []Encoding{EncodingRaw, EncodingRunLength, EncodingEliasFano} {
+		payload, _ := encodePayload(e, data, capacity)
+		for size := 0; size < len(payload); size++ {
+			if _, err := decodePayload(e, payload[:size], capacity); !errors.Is(err, ErrInvalidSerializedData) {
+				t.Errorf("encoding %d size %d: expected ErrInvalidSerializedData, got %v", e, size, err)
+			}
+		}
+
+		extra := append(append([]byte{}, payload...), 1, 1)
+		if _, err := decodePayload(e, extra, capacity); !errors.Is(err, ErrInvalidSerializedData) {
+			t.Errorf("encoding %d: expected ErrInvalidSerializedData, got %v", e, err)
+		}
+	}
+
+	invalid := [][]byte{
+		{0xff},
+		{2, 0, 0},
+		append([]byte{1, 0}, bytes.Repeat([]byte{0xff}, 126)...),
+	}
+	for _, payload := range invalid {
+		if _, err := decodeEliasFano(payload, capacity); !errors.Is(err, ErrInvalidSerializedData) {
+			t.Errorf("expected ErrInvalidSerializedData, got %v", err)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package bf
 import "errors"
 
 var ErrInvalidStorageCapacity = errors.New("invalid storage capacity")
+var ErrStorageCapacityTooLarge = errors.New("storage capacity is larger than the limit")
 
 var ErrStorageDifference = errors.New("storage is not the same")
 var ErrHasherDifference = errors.New("hasher is not the same")
@@ -14,3 +15,19 @@ var ErrNilHasherFactory = errors.New("implementation of HasherFactory is nil")
 var ErrNilStorage = errors.New("implementation of Storage is nil")
 var ErrNilHasher = errors.New("implementation of Hasher is nil")
 var ErrNilBloomFilter = errors.New("implementation of BloomFilter is nil")
+var ErrNilEncoder = errors.New("implementation of Encoder is nil")
+
+var ErrUnsupportedHasher = errors.New("hasher is not supported for serialization")
+var ErrInvalidSerializedData = errors.New("invalid serialized data")
+var ErrUnsupportedSerializationVersion = errors.New("unsupported serialization version")
+
+var ErrSnapshotConfigDifference = errors.New("config of snapshot is not the same")
+var ErrInvalidLog = errors.New("invalid write-ahead log")
+var ErrUnsupportedEncoding = errors.New("unsupported encoding")
+var ErrEmptyBloomFilters = errors.New("no BloomFilter is given")
+var ErrUnsupportedBloomFilter = errors.New("implementation of BloomFilter is not supported")
+var ErrInvalidFoldFactor = errors.New("fold factor must be a power of two which divides the capacity")
+var ErrInvalidNumberOfShards = errors.New("number of shards must be positive")
+var ErrShardsDifference = errors.New("number of shards is not the same")
+var ErrReadOnlyStorage = errors.New("storage is read-only")
+var ErrUnknownIndexMapping = errors.New("index mapping of BloomFilter is unknown")
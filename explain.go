@@ -0,0 +1,159 @@
+package bf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+/*
+Explanation tells why a filter reports an item as present or absent: every hash
+key of the item, the Storage index it is mapped to and whether the bit at that
+index is set. The item exists only if every bit is set, a false positive is an
+item whose bits were all set by other items.
+*/
+type Explanation struct {
+	Item     []byte
+	Exists   bool
+	Capacity uint32
+	Mapping  IndexMapping
+	Probes   []Probe
+}
+
+// Probe is a hash key of the explained item, SharedBy holds items of the sample
+// which are mapped to the same index.
+type Probe struct {
+	Key      Key
+	Index    uint32
+	Set      bool
+	SharedBy [][]byte
+}
+
+/*
+Explain hashes the item like Exists and returns which bits of the filter it
+checks. Items of the optional sample, for example items known to be added, are
+hashed as well and listed in every Probe they share an index with, the item
+itself is skipped. Filters which are not built-in are assumed to use
+IndexMappingMultiplyShift. It is meant for debugging false positives, the
+filter is locked while the sample is hashed.
+*/
+func Explain(f ReadOnlyFilter, item []byte, sample ...[]byte) (Explanation, error) {
+	switch v := f.(type) {
+	case nil:
+		return Explanation{}, ErrNilBloomFilter
+	case *concurrentBloomFilter:
+		v.mu.RLock()
+		defer v.mu.RUnlock()
+
+		return v.filter.explain(item, sample), nil
+	case *DurableBloomFilter:
+		v.mu.RLock()
+		defer v.mu.RUnlock()
+
+		return v.filter.explain(item, sample), nil
+	}
+	if b, ok := bloomFilterOf(f); ok {
+		return b.explain(item, sample), nil
+	}
+
+	h := f.Hasher()
+	if h == nil {
+		return Explanation{}, ErrNilHasher
+	}
+	s := f.Storage()
+	if s == nil {
+		return Explanation{}, ErrNilStorage
+	}
+	m, _ := indexMappingOf(f)
+	return explain(h, s, m, item, sample), nil
+}
+
+func (b *bloomFilter) explain(item []byte, sample [][]byte) Explanation {
+	return explain(b.hasher, b.storage, b.option.mapping, item, sample)
+}
+
+func explain(h Hasher, s Storage, m IndexMapping, item []byte, sample [][]byte) Explanation {
+	capacity := s.Capacity()
+	e := Explanation{Item: item, Exists: true, Capacity: capacity, Mapping: m}
+
+	keys := h.Hash(item, 1)[0]
+	e.Probes = make([]Probe, len(keys))
+	probes := make(map[uint32][]int, len(keys))
+	for i, key := range keys {
+		index := m.index(key, capacity)
+		set := s.Get(index)
+		e.Probes[i] = Probe{Key: key, Index: index, Set: set}
+		e.Exists = e.Exists && set
+		probes[index] = append(probes[index], i)
+	}
+
+	for _, other := range sample {
+		if bytes.Equal(other, item) {
+			continue
+		}
+
+		// an item could be mapped to the same index by many keys
+		shared := make(map[int]bool)
+		for _, key := range h.Hash(other, 1)[0] {
+			for _, i := range probes[m.index(key, capacity)] {
+				if !shared[i] {
+					shared[i] = true
+					e.Probes[i].SharedBy = append(e.Probes[i].SharedBy, other)
+				}
+			}
+		}
+	}
+	return e
+}
+
+// SetBits returns the number of probes whose bit is set.
+func (e Explanation) SetBits() int {
+	count := 0
+	for _, p := range e.Probes {
+		if p.Set {
+			count++
+		}
+	}
+	return count
+}
+
+// Covered reports whether the item exists and every bit of it is shared by an
+// item of the sample, that is the sample explains a false positive.
+func (e Explanation) Covered() bool {
+	if !e.Exists {
+		return false
+	}
+	for _, p := range e.Probes {
+		if len(p.SharedBy) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Info returns a human-readable description of the explanation.
+func (e Explanation) Info() string {
+	var info []string
+	info = append(info, fmt.Sprintf("Explain %q", e.Item))
+	info = append(info, fmt.Sprintf("  - Exists: %v", e.Exists))
+	info = append(info, fmt.Sprintf("  - Storage capacity: %v bits, index mapping: %v", e.Capacity, e.Mapping))
+	info = append(info, fmt.Sprintf("  - Bits set: %v of %v", e.SetBits(), len(e.Probes)))
+	if e.Covered() {
+		info = append(info, "  - Every bit is shared by items of the sample, likely a false positive")
+	}
+
+	width := len(fmt.Sprint(e.Capacity))
+	fmtString := fmt.Sprintf("      key=%%10d index=%%%dd set=%%-5v", width)
+	for _, p := range e.Probes {
+		line := fmt.Sprintf(fmtString, p.Key, p.Index, p.Set)
+		if len(p.SharedBy) > 0 {
+			shared := make([]string, len(p.SharedBy))
+			for i, item := range p.SharedBy {
+				shared[i] = fmt.Sprintf("%q", item)
+			}
+			line += " shared by: " + strings.Join(shared, ", ")
+		}
+		info = append(info, line)
+	}
+	return strings.Join(info, "\n")
+}
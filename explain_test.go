@@ -0,0 +1,126 @@
+package bf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	cases := []struct {
+		name string
+		f    BloomFilter
+	}{
+		{name: "bitset", f: Must(WithCapacity(1<<12, 3))},
+		{name: "modulo", f: Must(WithCapacity(1000, 3), WithIndexMapping(IndexMappingModulo))},
+		{name: "concurrent", f: Must(WithCapacity(1<<12, 3), WithConcurrency())},
+		{name: "custom storage", f: Must(WithCapacity(1<<12, 3), WithStorage(sliceStorageFactory{}))},
+		{name: "durable", f: openDurableForTest(t, t.TempDir())},
+		{name: "other implementation", f: wrappedBloomFilter{Must(WithCapacity(1<<12, 3))}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.f.Add([]byte("added"))
+
+			e, err := Explain(tc.f, []byte("added"))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !e.Exists || e.SetBits() != len(e.Probes) {
+				t.Errorf("expected every bit of an added item is set, got %v of %v", e.SetBits(), len(e.Probes))
+			}
+			if len(e.Probes) != int(tc.f.Config().NumberOfHashFunctions()) {
+				t.Errorf("expected %v probes, got %v", tc.f.Config().NumberOfHashFunctions(), len(e.Probes))
+			}
+
+			keys := tc.f.Hasher().Hash([]byte("added"), 1)[0]
+			for i, p := range e.Probes {
+				if p.Key != keys[i] {
+					t.Errorf("expected key %v, got %v", keys[i], p.Key)
+				}
+				if p.Index != e.Mapping.index(keys[i], tc.f.Storage().Capacity()) {
+					t.Errorf("unexpected index %v of key %v", p.Index, p.Key)
+				}
+			}
+
+			e, err = Explain(tc.f, []byte("missing"))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if e.Exists != tc.f.Exists([]byte("missing")) {
+				t.Errorf("expected Exists %v, got %v", tc.f.Exists([]byte("missing")), e.Exists)
+			}
+		})
+	}
+}
+
+func TestExplain_SampleOfFalsePositive(t *testing.T) {
+	f := Must(WithCapacity(64, 2))
+	var sample [][]byte
+	for i := 0; i < 20; i++ {
+		item := []byte(fmt.Sprintf("item-%d", i))
+		f.Add(item)
+		sample = append(sample, item)
+	}
+
+	var fp []byte
+	for i := 20; fp == nil; i++ {
+		if item := []byte(fmt.Sprintf("item-%d", i)); f.Exists(item) {
+			fp = item
+		}
+	}
+
+	e, err := Explain(f, fp, sample...)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !e.Exists || !e.Covered() {
+		t.Fatalf("expected a false positive is covered by the sample")
+	}
+	for _, p := range e.Probes {
+		for _, item := range p.SharedBy {
+			if !isUint32sContains(f.(*bloomFilter).indexes(item), p.Index) {
+				t.Errorf("%s does not share index %v", item, p.Index)
+			}
+		}
+	}
+
+	info := e.Info()
+	if !strings.HasPrefix(info, fmt.Sprintf("Explain %q", fp)) || !strings.Contains(info, "likely a false positive") {
+		t.Errorf("unexpected info %v", info)
+	}
+	if len(strings.Split(info, "\n")) != 5+len(e.Probes) {
+		t.Errorf("expected a line for every probe, got %v", info)
+	}
+}
+
+func TestExplain_SampleSkipsTheItem(t *testing.T) {
+	f := Must(WithCapacity(1<<12, 3))
+	f.Add([]byte("a"))
+
+	e, _ := Explain(f, []byte("a"), []byte("a"))
+	for _, p := range e.Probes {
+		if len(p.SharedBy) != 0 {
+			t.Errorf("expected the item is not shared with itself, got %s", p.SharedBy)
+		}
+	}
+	if e.Covered() {
+		t.Errorf("expected not covered without other items")
+	}
+}
+
+func TestExplain_NilFilter(t *testing.T) {
+	if _, err := Explain(nil, []byte("a")); err != ErrNilBloomFilter {
+		t.Errorf("expected %v, got %v", ErrNilBloomFilter, err)
+	}
+}
+
+func isUint32sContains(values []uint32, v uint32) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package bf
+
+import "math/bits"
+
+/*
+Fold creates a new BloomFilter with capacity divided by factor by OR-ing every
+part of the Storage together, given filter is not changed. The factor must be a
+power of two which divides the capacity. An index i of the filter becomes
+i / factor in the result WithIndexMapping(IndexMappingMultiplyShift), or
+i % (capacity / factor) WithIndexMapping(IndexMappingModulo), so items added
+into the filter still exist in the result at a higher error rate. The result
+keeps the number of hash functions, key size, counters and options of the
+filter.
+*/
+func Fold(f BloomFilter, factor uint32) (BloomFilter, error) {
+	if f == nil {
+		return nil, ErrNilBloomFilter
+	}
+
+	unlock := rLock(f)
+	defer unlock()
+
+	b, ok := innerBloomFilter(f).(*bloomFilter)
+	if !ok {
+		return nil, ErrUnsupportedBloomFilter
+	}
+
+	capacity := b.storage.Capacity()
+	if factor == 0 || bits.OnesCount32(factor) != 1 || capacity%factor != 0 {
+		return nil, ErrInvalidFoldFactor
+	}
+
+	o := b.option
+	o.config = configWithMapping(config{
+		mode:            "capacity",
+		k:               b.option.config.NumberOfHashFunctions(),
+		storageCapacity: capacity / factor,
+		keySize:         b.option.config.KeySize(),
+	}, o.mapping)
+	r, err := newBloomFilter(o)
+	if err != nil {
+		return nil, err
+	}
+
+	foldStorage(r.storage, b.storage, factor, o.mapping)
+	r.count = b.count
+	r.distinct = b.distinct
+	return o.wrap(r), nil
+}
+
+func foldStorage(dst, src Storage, factor uint32, mapping IndexMapping) {
+	if mapping == IndexMappingMultiplyShift {
+		foldStorageByDivision(dst, src, factor)
+		return
+	}
+
+	capacity := dst.Capacity()
+	d, dOk := dst.(*bitset)
+	s, sOk := src.(*bitset)
+	if dOk && sOk && capacity%bitsetDataSize == 0 {
+		words := s.words()
+		for i := 0; i < len(words); i += len(d.data) {
+			orWords(d.data, words[i:i+len(d.data)])
+		}
+		return
+	}
+
+	for i := uint32(0); i < src.Capacity(); i++ {
+		if src.Get(i) {
+			dst.Set(i % capacity)
+		}
+	}
+}
+
+// foldStorageByDivision sets bit i / factor of dst for every bit i set in src.
+func foldStorageByDivision(dst, src Storage, factor uint32) {
+	if s, ok := src.(*bitset); ok {
+		for n, word := range s.words() {
+			for word != 0 {
+				i := uint32(n)*bitsetDataSize + uint32(bits.TrailingZeros64(word))
+				dst.Set(i / factor)
+				word &= word - 1
+			}
+		}
+		return
+	}
+
+	for i := uint32(0); i < src.Capacity(); i++ {
+		if src.Get(i) {
+			dst.Set(i / factor)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package bf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFold_ReturnsErrIfFactorIsInvalid(t *testing.T) {
+	if _, err := Fold(nil, 2); !errors.Is(err, ErrNilBloomFilter) {
+		t.Errorf("expected %v, got %v", ErrNilBloomFilter, err)
+	}
+
+	f := Must(WithCapacity(96, 3))
+	for _, factor := range []uint32{0, 3, 6, 64} {
+		if _, err := Fold(f, factor); !errors.Is(err, ErrInvalidFoldFactor) {
+			t.Errorf("factor %v: expected %v, got %v", factor, ErrInvalidFoldFactor, err)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	cases := []struct {
+		name     string
+		capacity uint32
+		factor   uint32
+		opts     []OptionFunc
+	}{
+		{name: "factor 1", capacity: 1 << 16, factor: 1},
+		{name: "bitset words", capacity: 1 << 16, factor: 2},
+		{name: "bitset words by 8", capacity: 1 << 16, factor: 8},
+		{name: "bitset bits", capacity: 96, factor: 4},
+		{name: "fnv", capacity: 1 << 12, factor: 4, opts: []OptionFunc{WithFNV()}},
+		{name: "concurrent", capacity: 1 << 12, factor: 2, opts: []OptionFunc{WithConcurrency()}},
+		{name: "custom storage", capacity: 1 << 12, factor: 4, opts: []OptionFunc{WithStorage(sliceStorageFactory{})}},
+		{name: "modulo words", capacity: 1 << 16, factor: 8, opts: []OptionFunc{WithIndexMapping(IndexMappingModulo)}},
+		{name: "modulo bits", capacity: 96, factor: 4, opts: []OptionFunc{WithIndexMapping(IndexMappingModulo)}},
+		{name: "modulo custom storage", capacity: 1 << 12, factor: 4, opts: []OptionFunc{
+			WithIndexMapping(IndexMappingModulo), WithStorage(sliceStorageFactory{}),
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := Must(WithCapacity(tc.capacity, 3), tc.opts...)
+			for i := 0; i < 20; i++ {
+				f.Add([]byte(fmt.Sprintf("item-%d", i)))
+			}
+			before, _ := Marshal(f)
+
+			r, err := Fold(f, tc.factor)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			if r.Storage().Capacity() != tc.capacity/tc.factor {
+				t.Errorf("expected capacity %v, got %v", tc.capacity/tc.factor, r.Storage().Capacity())
+			}
+			if r.Config().KeySize() != f.Config().KeySize() {
+				t.Errorf("expected key size %v, got %v", f.Config().KeySize(), r.Config().KeySize())
+			}
+			if r.Config().NumberOfHashFunctions() != 3 || r.Count() != 20 {
+				t.Errorf("expected number of hash functions and count are kept")
+			}
+			for i := 0; i < 20; i++ {
+				if !r.Exists([]byte(fmt.Sprintf("item-%d", i))) {
+					t.Errorf("expected item-%d exists", i)
+				}
+			}
+
+			// the folded filter must be the same as a filter of the folded capacity
+			expected := Must(r.Config(), tc.opts...)
+			for i := 0; i < 20; i++ {
+				expected.Add([]byte(fmt.Sprintf("item-%d", i)))
+			}
+			for i := uint32(0); i < r.Storage().Capacity(); i++ {
+				if r.Storage().Get(i) != expected.Storage().Get(i) {
+					t.Fatalf("unexpected bit %v", i)
+				}
+			}
+
+			after, _ := Marshal(f)
+			if string(before) != string(after) {
+				t.Errorf("expected given filter is not changed")
+			}
+		})
+	}
+}
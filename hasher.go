@@ -1,5 +1,7 @@
 package bf
 
+import "encoding/binary"
+
 type HasherFactory interface {
 	Make(numberOfHashFunctions, hashSizeInBits byte) Hasher
 }
@@ -26,39 +28,40 @@ func (h *hasher) makeKeySplitter(count int, input []byte, hashFn func(*[]byte) [
 		times++
 	}
 	return &KeySplitter{
-		Source:   h.hashNTimes(byte(times), &input, hashFn),
+		Source:   h.hashNTimes(times, &input, hashFn),
 		Count:    count,
 		KeyCount: keyCount,
 		KeySize:  h.keySize,
 	}
 }
 
-func (h *hasher) hashNTimes(n byte, input *[]byte, fn func(*[]byte) []byte) []byte {
+/*
+hashNTimes returns n hashes of the input as a stream in counter mode: the first
+hash is of the input, the next ones are of the input prefixed by a counter 0, 1,
+2... encoded as uvarint so the stream could be as long as needed. A counter
+below 128 is encoded in a single byte, keys of existing filters are unchanged.
+*/
+func (h *hasher) hashNTimes(n int, input *[]byte, fn func(*[]byte) []byte) []byte {
 	if n == 1 {
 		return fn(input)
 	}
 
-	var result = make([]byte, int(n)*h.hashSizeInBytes)
-	l := len(*input)
-	item := make([]byte, l+1)
-	for c := 0; c < l; c++ {
-		item[c+1] = (*input)[c]
+	var result = make([]byte, n*h.hashSizeInBytes)
+	if n == 0 {
+		return result
 	}
+	copy(result, fn(input))
 
-	for i := byte(0); i < n; i++ {
-		if i == 0 {
-			src := fn(input)
-			for j := 0; j < h.hashSizeInBytes; j++ {
-				result[h.hashSizeInBytes*int(i)+j] = src[j]
-			}
-			continue
-		}
-
-		item[0] = i - 1
-		src := fn(&item)
-		for j := 0; j < h.hashSizeInBytes; j++ {
-			result[h.hashSizeInBytes*int(i)+j] = src[j]
-		}
+	// the counter is written right before the input so it could grow in place
+	const offset = binary.MaxVarintLen64
+	var counter [binary.MaxVarintLen64]byte
+	buf := make([]byte, offset+len(*input))
+	copy(buf[offset:], *input)
+	for i := 1; i < n; i++ {
+		size := binary.PutUvarint(counter[:], uint64(i-1))
+		copy(buf[offset-size:], counter[:size])
+		item := buf[offset-size:]
+		copy(result[i*h.hashSizeInBytes:(i+1)*h.hashSizeInBytes], fn(&item))
 	}
 	return result
 }
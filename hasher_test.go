@@ -178,7 +178,7 @@ func TestHasher_MakeKeySplitter(t *testing.T) {
 func TestHasher_HashNTimes(t *testing.T) {
 	cases := []struct {
 		name               string
-		n                  byte
+		n                  int
 		hashSizeInBytes    int
 		input              []byte
 		mockedReturn       map[int][]byte
@@ -253,6 +253,55 @@ func TestHasher_HashNTimes(t *testing.T) {
 	}
 }
 
+func TestHasher_HashNTimes_UvarintCounter(t *testing.T) {
+	h := &hasher{hashSizeInBytes: 1}
+	var calledWith [][]byte
+	fn := func(input *[]byte) []byte {
+		calledWith = append(calledWith, append([]byte{}, *input...))
+		return []byte{byte(len(calledWith))}
+	}
+
+	result := h.hashNTimes(131, &[]byte{9, 9}, fn)
+	if len(result) != 131 || result[130] != 131 {
+		t.Errorf("expected every hash is kept, got %v", result)
+	}
+
+	expected := map[int][]byte{
+		0:   {9, 9},
+		1:   {0, 9, 9},
+		128: {127, 9, 9},
+		129: {0x80, 0x01, 9, 9},
+		130: {0x81, 0x01, 9, 9},
+	}
+	for i, e := range expected {
+		if !bytes.Equal(calledWith[i], e) {
+			t.Errorf("call %d: expected %v, got %v", i, e, calledWith[i])
+		}
+	}
+}
+
+func TestBuiltinHashers_HashLargeCount(t *testing.T) {
+	for _, hf := range []HasherFactory{shaHasherFactory{}, fnvHasherFactory{}} {
+		h := hf.Make(7, 32)
+		const count = 5000
+		keys := h.Hash([]byte(hasherInput), count)
+		if len(keys) != count || len(keys[count-1]) != 7 {
+			t.Fatalf("%T: expected %d x 7 keys, got %d", h, count, len(keys))
+		}
+
+		// every row must come from a different part of the stream
+		seen := make(map[[7]Key]int)
+		for i, row := range keys {
+			var r [7]Key
+			copy(r[:], row)
+			if j, ok := seen[r]; ok {
+				t.Fatalf("%T: row %d repeats row %d", h, i, j)
+			}
+			seen[r] = i
+		}
+	}
+}
+
 const hasherInput = "hello"
 
 func runTestHasherDoHash(t *testing.T, hasher hasher, hashFn func(*[]byte) []byte, h, h0, h1, h2, h3 string) {
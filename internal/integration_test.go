@@ -214,6 +214,7 @@ func TestBloomFilter_FalsePositiveRate_WithCapacity(t *testing.T) {
 func TestBloomFilter_FalsePositiveRate_WithAccuracy(t *testing.T) {
 	requested := []float64{0.05, 0.02, 0.01, 0.005, 0.002, 0.001, 0.0001}
 	for _, e := range requested {
+		e := e
 		t.Run(fmt.Sprintf("Check false positive rate with requested error rate %v - SHA", e), func(t *testing.T) {
 			t.Parallel()
 			var n = 1_000_000
@@ -252,3 +253,82 @@ func runTestBloomFilterFalsePositiveRateWithAccuracy(
 		t.Skipf("False positive error rate is 2x greater than requested. Requested %v, actual %v", requestedErrorRate, rate)
 	}
 }
+
+type countingStorage struct {
+	hits []int
+}
+
+func (s *countingStorage) Set(index uint32) {
+	s.hits[index]++
+}
+
+func (s *countingStorage) Clear(index uint32) {}
+
+func (s *countingStorage) Get(index uint32) bool {
+	return s.hits[index] > 0
+}
+
+func (s *countingStorage) Capacity() uint32 {
+	return uint32(len(s.hits))
+}
+
+func (s *countingStorage) Equals(other bf.Storage) bool {
+	return false
+}
+
+type countingStorageFactory struct {
+	storage *countingStorage
+}
+
+func (f *countingStorageFactory) Make(capacity uint32) (bf.Storage, error) {
+	f.storage = &countingStorage{hits: make([]int, capacity)}
+	return f.storage, nil
+}
+
+// chiSquare returns the chi-square statistic of hits against the uniform
+// distribution.
+func chiSquare(hits []int) float64 {
+	total := 0
+	for _, h := range hits {
+		total += h
+	}
+	expected := float64(total) / float64(len(hits))
+
+	var result float64
+	for _, h := range hits {
+		d := float64(h) - expected
+		result += d * d / expected
+	}
+	return result
+}
+
+func TestBloomFilter_IndexUniformity(t *testing.T) {
+	t.Parallel()
+	// a capacity just above a power of two, keys of 13 bits hit indexes below
+	// 8192 - 5120 twice as often by modulo
+	var m uint32 = 1<<12 + 1<<10
+	n, k := 100_000, byte(4)
+	df := float64(m - 1)
+	// the statistic is about normal with mean df and variance 2*df, p < 0.0001
+	critical := df + 4*math.Sqrt(2*df)
+
+	cases := []struct {
+		mapping bf.IndexMapping
+		uniform bool
+	}{
+		{mapping: bf.IndexMappingMultiplyShift, uniform: true},
+		{mapping: bf.IndexMappingModulo, uniform: false},
+	}
+	for _, tc := range cases {
+		sf := &countingStorageFactory{}
+		filter := bf.Must(bf.WithCapacity(m, k), bf.WithStorage(sf), bf.WithIndexMapping(tc.mapping))
+		for i := 0; i < n; i++ {
+			filter.Add([]byte(RandString(12)))
+		}
+
+		x := chiSquare(sf.storage.hits)
+		if (x < critical) != tc.uniform {
+			t.Errorf("%v: expected uniform %v, chi-square %.1f, critical value %.1f", tc.mapping, tc.uniform, x, critical)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package bf
+
+/*
+KeyHandle is an item hashed by BloomFilter.Keys, it could be given to AddKeys
+and ExistsKeys of any filter with the same capacity, IndexMapping and a hasher
+which Equals the one of the filter it was made by, so the item is hashed once
+for many filters. Other filters return ErrStorageDifference or
+ErrHasherDifference. The item must not be modified while the handle is in use.
+*/
+type KeyHandle struct {
+	item     []byte
+	hasher   Hasher
+	capacity uint32
+	mapping  IndexMapping
+	indexes  []uint32
+}
+
+/*
+NewKeyHandle makes a KeyHandle of an item which is not hashed, every filter
+hashes the item when the handle is used. It is used by BloomFilter
+implementations which cannot hash items locally.
+*/
+func NewKeyHandle(item []byte) KeyHandle {
+	return KeyHandle{item: item}
+}
+
+// Item returns the item of the handle.
+func (h KeyHandle) Item() []byte {
+	return h.item
+}
+
+// Hashed reports whether the item was hashed when the handle was made.
+func (h KeyHandle) Hashed() bool {
+	return h.hasher != nil
+}
+
+func (b *bloomFilter) Keys(item []byte) KeyHandle {
+	return KeyHandle{
+		item:     item,
+		hasher:   b.hasher,
+		capacity: b.storage.Capacity(),
+		mapping:  b.option.mapping,
+		indexes:  b.indexes(item),
+	}
+}
+
+func (b *bloomFilter) AddKeys(h KeyHandle) error {
+	indexes, err := b.indexesOf(h)
+	if err != nil {
+		return err
+	}
+
+	if !b.option.distinctCount {
+		for _, index := range indexes {
+			b.storage.Set(index)
+		}
+		b.inserted(true)
+		return nil
+	}
+
+	existed := true
+	for _, index := range indexes {
+		if !b.storage.Get(index) {
+			existed = false
+			b.storage.Set(index)
+		}
+	}
+	b.inserted(existed)
+	return nil
+}
+
+func (b *bloomFilter) ExistsKeys(h KeyHandle) (bool, error) {
+	indexes, err := b.indexesOf(h)
+	if err != nil {
+		return false, err
+	}
+
+	for _, index := range indexes {
+		if !b.storage.Get(index) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// indexesOf returns Storage indexes of the handle, the item is hashed if the
+// handle was made by NewKeyHandle.
+func (b *bloomFilter) indexesOf(h KeyHandle) ([]uint32, error) {
+	if !h.Hashed() {
+		return b.indexes(h.item), nil
+	}
+	if err := h.check(b.hasher, b.storage.Capacity(), b.option.mapping); err != nil {
+		return nil, err
+	}
+	return h.indexes, nil
+}
+
+// check returns an error if the handle was hashed for a filter with another
+// capacity, hasher or IndexMapping.
+func (h KeyHandle) check(hasher Hasher, capacity uint32, mapping IndexMapping) error {
+	if h.capacity != capacity {
+		return ErrStorageDifference
+	}
+	if h.mapping != mapping || !h.hasher.Equals(hasher) {
+		return ErrHasherDifference
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package bf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type countingHasher struct {
+	shaHasher
+	calls int
+}
+
+func (c *countingHasher) Hash(input []byte, count int) [][]Key {
+	c.calls++
+	return c.shaHasher.Hash(input, count)
+}
+
+func (c *countingHasher) Equals(other Hasher) bool {
+	o, ok := other.(*countingHasher)
+	return ok && o.hasher == c.hasher
+}
+
+type countingHasherFactory struct {
+	hashers []*countingHasher
+}
+
+func (f *countingHasherFactory) Make(numberOfHashFunctions, hashSizeInBits byte) Hasher {
+	h := shaHasherFactory{}.Make(numberOfHashFunctions, hashSizeInBits).(*shaHasher)
+	r := &countingHasher{shaHasher: *h}
+	f.hashers = append(f.hashers, r)
+	return r
+}
+
+func TestKeyHandle_ReusedAcrossFilters(t *testing.T) {
+	hf := &countingHasherFactory{}
+	filters := []BloomFilter{
+		Must(WithCapacity(4096, 3), WithHasher(hf)),
+		Must(WithCapacity(4096, 3), WithHasher(hf), WithConcurrency()),
+		Must(WithCapacity(4096, 3), WithHasher(hf), WithDistinctCount()),
+	}
+
+	for i := 0; i < 10; i++ {
+		h := filters[0].Keys([]byte(fmt.Sprintf("item-%d", i)))
+		for _, f := range filters {
+			if err := f.AddKeys(h); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if ok, err := f.ExistsKeys(h); !ok || err != nil {
+				t.Errorf("expected true, got %v, %v", ok, err)
+			}
+		}
+	}
+
+	if hf.hashers[0].calls != 10 || hf.hashers[1].calls != 0 || hf.hashers[2].calls != 0 {
+		t.Errorf("expected items are hashed once, got %v, %v, %v", hf.hashers[0].calls, hf.hashers[1].calls, hf.hashers[2].calls)
+	}
+	for _, f := range filters {
+		for i := 0; i < 10; i++ {
+			if !f.Exists([]byte(fmt.Sprintf("item-%d", i))) {
+				t.Errorf("expected item-%d exists", i)
+			}
+		}
+		if f.Count() != 10 {
+			t.Errorf("expected count 10, got %v", f.Count())
+		}
+	}
+	if ok, _ := filters[1].ExistsKeys(filters[0].Keys([]byte("other"))); ok {
+		t.Errorf("expected other does not exist")
+	}
+}
+
+func TestKeyHandle_ReturnsErrIfFilterIsDifferent(t *testing.T) {
+	h := Must(WithCapacity(4096, 3)).Keys([]byte("item"))
+
+	cases := []struct {
+		name     string
+		filter   BloomFilter
+		expected error
+	}{
+		{name: "capacity", filter: Must(WithCapacity(2048, 3)), expected: ErrStorageDifference},
+		{name: "number of hash functions", filter: Must(WithCapacity(4096, 4)), expected: ErrHasherDifference},
+		{name: "hasher", filter: Must(WithCapacity(4096, 3), WithFNV()), expected: ErrHasherDifference},
+		{name: "index mapping", filter: Must(WithCapacity(4096, 3), WithIndexMapping(IndexMappingModulo)), expected: ErrHasherDifference},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.filter.AddKeys(h); !errors.Is(err, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+			if _, err := tc.filter.ExistsKeys(h); !errors.Is(err, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+			if tc.filter.Count() != 0 {
+				t.Errorf("expected filter is not changed")
+			}
+		})
+	}
+}
+
+func TestNewKeyHandle_IsHashedByEveryFilter(t *testing.T) {
+	h := NewKeyHandle([]byte("item"))
+	if h.Hashed() || string(h.Item()) != "item" {
+		t.Errorf("unexpected handle")
+	}
+
+	for _, f := range []BloomFilter{Must(WithCapacity(4096, 3)), Must(WithCapacity(1000, 5), WithFNV())} {
+		if err := f.AddKeys(h); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if ok, _ := f.ExistsKeys(h); !ok || !f.Exists([]byte("item")) {
+			t.Errorf("expected item exists")
+		}
+	}
+}
+
+func TestDurableBloomFilter_AddKeys(t *testing.T) {
+	dir := t.TempDir()
+	d := openDurableForTest(t, dir)
+	h := Must(WithCapacity(4096, 3)).Keys([]byte("item-0"))
+	if err := d.AddKeys(h); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if ok, err := d.ExistsKeys(d.Keys([]byte("item-0"))); !ok || err != nil {
+		t.Errorf("expected true, got %v, %v", ok, err)
+	}
+	if err := d.AddKeys(Must(WithCapacity(1024, 3)).Keys([]byte("x"))); !errors.Is(err, ErrStorageDifference) {
+		t.Errorf("expected ErrStorageDifference, got %v", err)
+	}
+	_ = d.Close()
+
+	r := openDurableForTest(t, dir)
+	defer r.Close()
+	assertDurableContains(t, r, 1)
+}
@@ -0,0 +1,148 @@
+package bf
+
+import (
+	"runtime"
+	"sync"
+)
+
+const parallelMergeMinWords = 1 << 14
+
+/*
+UnionAll creates a new BloomFilter which is the union of all given filters,
+given filters are not changed. They must use the same Storage and Hash, the
+result uses the Config and options of the first filter. Word ranges of bitset
+storages are merged in parallel for big filters.
+*/
+func UnionAll(filters ...BloomFilter) (BloomFilter, error) {
+	return mergeAll(filters, orWords, BloomFilter.Union)
+}
+
+/*
+IntersectAll creates a new BloomFilter which is the intersection of all given
+filters, given filters are not changed. They must use the same Storage and
+Hash, the result uses the Config and options of the first filter. Word ranges
+of bitset storages are merged in parallel for big filters.
+*/
+func IntersectAll(filters ...BloomFilter) (BloomFilter, error) {
+	return mergeAll(filters, andWords, BloomFilter.Intersect)
+}
+
+func mergeAll(
+	filters []BloomFilter,
+	op func(dst, src []uint64),
+	fallback func(BloomFilter, ReadOnlyFilter) error,
+) (BloomFilter, error) {
+	if len(filters) == 0 {
+		return nil, ErrEmptyBloomFilters
+	}
+
+	first := filters[0]
+	if first == nil {
+		return nil, ErrNilBloomFilter
+	}
+	for _, f := range filters[1:] {
+		if err := Compatible(first, f); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := first.Clone()
+	if err != nil || len(filters) == 1 {
+		return r, err
+	}
+
+	sources := stableAll(filters[1:])
+	target := innerBloomFilter(r)
+	if b, ok := target.(*bloomFilter); ok {
+		if dst, ok := b.storage.(*bitset); ok {
+			if srcs, ok := wordsOf(sources); ok {
+				mergeWords(dst.data, srcs, op)
+				b.merged()
+				return r, nil
+			}
+		}
+	}
+
+	for _, f := range sources {
+		if err = fallback(target, f); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func wordsOf(filters []ReadOnlyFilter) ([][]uint64, bool) {
+	result := make([][]uint64, len(filters))
+	for i, f := range filters {
+		b, ok := f.Storage().(*bitset)
+		if !ok {
+			return nil, false
+		}
+		result[i] = b.words()
+	}
+	return result, true
+}
+
+func mergeWords(dst []uint64, srcs [][]uint64, op func(dst, src []uint64)) {
+	workers := runtime.GOMAXPROCS(0)
+	if len(dst) < parallelMergeMinWords || workers == 1 {
+		for _, src := range srcs {
+			op(dst, src)
+		}
+		return
+	}
+
+	size := (len(dst) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for lo := 0; lo < len(dst); lo += size {
+		hi := lo + size
+		if hi > len(dst) {
+			hi = len(dst)
+		}
+
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			for _, src := range srcs {
+				op(dst[lo:hi], src[lo:hi])
+			}
+		}(lo, hi)
+	}
+	wg.Wait()
+}
+
+// stableAll returns filters which could be read without locks, concurrent and
+// durable filters are replaced by snapshots which are taken under their own
+// lock one at a time, so merges of the same filters in any order do not
+// deadlock.
+func stableAll(filters []BloomFilter) []ReadOnlyFilter {
+	result := make([]ReadOnlyFilter, len(filters))
+	for i, f := range filters {
+		result[i] = stable(f)
+	}
+	return result
+}
+
+// stable returns a snapshot of a concurrent or durable filter, other filters
+// are returned as they are.
+func stable(f ReadOnlyFilter) ReadOnlyFilter {
+	switch v := f.(type) {
+	case *concurrentBloomFilter:
+		return v.Snapshot()
+	case *DurableBloomFilter:
+		return v.Snapshot()
+	}
+	return f
+}
+
+// rLock holds the read lock of a concurrent filter so its storage could be read
+// directly. Filters are locked one at a time, see stableAll.
+func rLock(f BloomFilter) func() {
+	c, ok := f.(*concurrentBloomFilter)
+	if !ok {
+		return func() {}
+	}
+
+	c.mu.RLock()
+	return c.mu.RUnlock
+}
@@ -0,0 +1,178 @@
+package bf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type sliceStorage struct {
+	data []bool
+}
+
+func (s *sliceStorage) Set(index uint32) {
+	s.data[index] = true
+}
+
+func (s *sliceStorage) Clear(index uint32) {
+	s.data[index] = false
+}
+
+func (s *sliceStorage) Get(index uint32) bool {
+	return s.data[index]
+}
+
+func (s *sliceStorage) Capacity() uint32 {
+	return uint32(len(s.data))
+}
+
+func (s *sliceStorage) Equals(other Storage) bool {
+	o, ok := other.(*sliceStorage)
+	return ok && len(o.data) == len(s.data)
+}
+
+type sliceStorageFactory struct{}
+
+func (sliceStorageFactory) Make(capacity uint32) (Storage, error) {
+	return &sliceStorage{data: make([]bool, capacity)}, nil
+}
+
+func makeFiltersForMergeTest(count int, cf Config, opts ...OptionFunc) []BloomFilter {
+	result := make([]BloomFilter, count)
+	for i := range result {
+		result[i] = Must(cf, opts...)
+		result[i].Add([]byte("shared"))
+		result[i].Add([]byte(fmt.Sprintf("item-%d", i)))
+	}
+	return result
+}
+
+func TestUnionAll_IntersectAll_ReturnErrIfFiltersAreInvalid(t *testing.T) {
+	a := Must(WithCapacity(1000, 3))
+	cases := []struct {
+		name     string
+		filters  []BloomFilter
+		expected error
+	}{
+		{name: "empty", filters: nil, expected: ErrEmptyBloomFilters},
+		{name: "nil first", filters: []BloomFilter{nil, a}, expected: ErrNilBloomFilter},
+		{name: "nil other", filters: []BloomFilter{a, nil}, expected: ErrNilBloomFilter},
+		{name: "storage", filters: []BloomFilter{a, Must(WithCapacity(999, 3))}, expected: ErrStorageDifference},
+		{name: "hasher", filters: []BloomFilter{a, Must(WithCapacity(1000, 3), WithFNV())}, expected: ErrHasherDifference},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, fn := range []func(...BloomFilter) (BloomFilter, error){UnionAll, IntersectAll} {
+				r, err := fn(tc.filters...)
+				if r != nil || !errors.Is(err, tc.expected) {
+					t.Errorf("expected %v, got %v", tc.expected, err)
+				}
+			}
+		})
+	}
+}
+
+func TestUnionAll_IntersectAll(t *testing.T) {
+	cases := []struct {
+		name   string
+		config Config
+		opts   []OptionFunc
+	}{
+		{name: "bitset", config: WithCapacity(4096, 3)},
+		{name: "parallel bitset", config: WithCapacity(parallelMergeMinWords*bitsetDataSize*2, 3)},
+		{name: "concurrent", config: WithCapacity(4096, 3), opts: []OptionFunc{WithConcurrency()}},
+		{name: "custom storage", config: WithCapacity(4096, 3), opts: []OptionFunc{WithStorage(sliceStorageFactory{})}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			filters := makeFiltersForMergeTest(16, tc.config, tc.opts...)
+			filters = append(filters, filters[3])
+			before, _ := Marshal(filters[0])
+
+			union, err := UnionAll(filters...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			intersect, err := IntersectAll(filters...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			for i := 0; i < 16; i++ {
+				item := []byte(fmt.Sprintf("item-%d", i))
+				if !union.Exists(item) {
+					t.Errorf("expected %s exists in union", item)
+				}
+				if intersect.Exists(item) {
+					t.Errorf("expected %s does not exist in intersection", item)
+				}
+			}
+			if !union.Exists([]byte("shared")) || !intersect.Exists([]byte("shared")) {
+				t.Errorf("expected shared exists")
+			}
+			if union.Count() != -1 || intersect.Count() != -1 {
+				t.Errorf("expected count is -1")
+			}
+
+			after, _ := Marshal(filters[0])
+			if string(before) != string(after) || filters[0].Count() != 2 {
+				t.Errorf("expected given filters are not changed")
+			}
+			if union == filters[0] || union.Storage() == filters[0].Storage() {
+				t.Errorf("expected a new filter")
+			}
+		})
+	}
+}
+
+func TestUnionAll_SingleFilterReturnsClone(t *testing.T) {
+	a := Must(WithCapacity(1000, 3))
+	a.Add([]byte("a"))
+
+	r, err := UnionAll(a)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if r == a || !r.Exists([]byte("a")) || r.Count() != 1 {
+		t.Errorf("expected a clone of the given filter")
+	}
+}
+
+func TestUnionAll_InAnyOrderWithWritersDoesNotDeadlock(t *testing.T) {
+	a := Must(WithCapacity(4096, 3), WithConcurrency())
+	b := Must(WithCapacity(4096, 3), WithConcurrency())
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, pair := range [][]BloomFilter{{a, b}, {b, a}} {
+		wg.Add(2)
+		go func(filters []BloomFilter) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if _, err := UnionAll(filters[0], filters[0], filters[1]); err != nil {
+					t.Errorf("expected nil, got %v", err)
+				}
+			}
+		}(pair)
+		go func(f BloomFilter) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				f.Add([]byte(fmt.Sprintf("item-%d", i)))
+			}
+		}(pair[0])
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected merges in any order do not deadlock")
+	}
+}
@@ -1,21 +1,40 @@
 package bf
 
+import "fmt"
+
 type Option struct {
 	config         Config
 	storageFactory StorageFactory
 	hasherFactory  HasherFactory
+	concurrent     bool
+	distinctCount  bool
+	mapping        IndexMapping
+	maxCapacity    uint32
 }
 
 type OptionFunc func(option *Option)
 
 /*
 New BloomFilter instance with Config could be the built-in WithAccuracy or
-WithCapacity configuration. Options including WithStorage, WithHasher or a
+WithCapacity configuration. Options including WithStorage, WithHasher, WithConcurrency or a
 built-in hash strategy WithSHA (default) and WithFNV.
 */
 func New(config Config, opts ...OptionFunc) (BloomFilter, error) {
+	o, err := newOption(config, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newBloomFilter(o)
+	if err != nil {
+		return nil, err
+	}
+	return o.wrap(r), nil
+}
+
+func newOption(config Config, opts []OptionFunc) (Option, error) {
 	if config == nil {
-		return nil, ErrNilConfig
+		return Option{}, ErrNilConfig
 	}
 
 	o := Option{
@@ -25,24 +44,53 @@ func New(config Config, opts ...OptionFunc) (BloomFilter, error) {
 	}
 	for _, opt := range opts {
 		if opt == nil {
-			return nil, ErrNilOptionFunc
+			return Option{}, ErrNilOptionFunc
 		}
 		opt(&o)
 	}
 
 	if o.storageFactory == nil {
-		return nil, ErrNilStorageFactory
+		return Option{}, ErrNilStorageFactory
 	}
 
 	if o.hasherFactory == nil {
-		return nil, ErrNilHasherFactory
+		return Option{}, ErrNilHasherFactory
 	}
 
-	r, err := newBloomFilter(o)
-	if err != nil {
-		return nil, err
+	if err := o.checkCapacity(config.StorageCapacity()); err != nil {
+		return Option{}, err
+	}
+	o.config = configWithMapping(o.config, o.mapping)
+	return o, nil
+}
+
+// configWithMapping returns the built-in Config which reports the key size of
+// a filter with the given IndexMapping, other Config are returned as they are.
+func configWithMapping(c Config, m IndexMapping) Config {
+	if v, ok := c.(config); ok {
+		v.multiplyShift = m == IndexMappingMultiplyShift
+		return v
+	}
+	return c
+}
+
+// keySizeOf returns the size in bits of keys made by the hasher of f, it is
+// KeySize of the Config for filters which are not built-in.
+func keySizeOf(f ReadOnlyFilter, c Config) byte {
+	if b, ok := bloomFilterOf(f); ok {
+		return b.option.keySize()
+	}
+	if d, ok := f.(*DurableBloomFilter); ok {
+		return d.filter.option.keySize()
+	}
+	return c.KeySize()
+}
+
+func (o Option) wrap(f *bloomFilter) BloomFilter {
+	if o.concurrent {
+		return &concurrentBloomFilter{filter: f}
 	}
-	return r, nil
+	return f
 }
 
 func newBloomFilter(o Option) (*bloomFilter, error) {
@@ -54,17 +102,34 @@ func newBloomFilter(o Option) (*bloomFilter, error) {
 		return nil, ErrNilStorage
 	}
 
-	h := o.hasherFactory.Make(o.config.NumberOfHashFunctions(), o.config.KeySize())
+	h, err := o.hasher()
+	if err != nil {
+		return nil, err
+	}
+	return &bloomFilter{option: o, storage: storage, hasher: h, count: 0}, nil
+}
+
+func (o Option) hasher() (Hasher, error) {
+	h := o.hasherFactory.Make(o.config.NumberOfHashFunctions(), o.keySize())
 	if h == nil {
 		return nil, ErrNilHasher
 	}
-	return &bloomFilter{option: o, storage: storage, hasher: h, count: 0}, nil
+	return h, nil
+}
+
+// keySize is the size in bits of keys made by the hasher, multiply-shift needs
+// full 32-bit keys to spread them evenly over the capacity.
+func (o Option) keySize() byte {
+	if o.mapping == IndexMappingMultiplyShift {
+		return 32
+	}
+	return o.config.KeySize()
 }
 
 /*
 Must create new BloomFilter instance with Config could be the built-in
 WithAccuracy or WithCapacity configuration. Options including WithStorage,
-WithHasher or a built-in hash strategy WithSHA (default) and WithFNV.
+WithHasher, WithConcurrency or a built-in hash strategy WithSHA (default) and WithFNV.
 */
 func Must(config Config, opts ...OptionFunc) BloomFilter {
 	f, err := New(config, opts...)
@@ -97,3 +162,103 @@ func WithFNV() OptionFunc {
 		o.hasherFactory = fnvHasherFactory{}
 	}
 }
+
+/*
+WithConcurrency makes the BloomFilter safe for concurrent use by multiple
+goroutines. Storage() of the filter is not guarded and should not be modified
+directly while the filter is in use.
+*/
+func WithConcurrency() OptionFunc {
+	return func(o *Option) {
+		o.concurrent = true
+	}
+}
+
+/*
+WithDistinctCount makes Count return DistinctCount, the number of added items
+which flipped at least one bit, instead of the number of Add calls. Add checks
+every bit before setting it. The number of distinct items is estimated from the
+number of set bits after filters are merged by Union or Intersect.
+*/
+func WithDistinctCount() OptionFunc {
+	return func(o *Option) {
+		o.distinctCount = true
+	}
+}
+
+/*
+IndexMapping is the way a key of the hasher is mapped to an index of the
+Storage.
+
+IndexMappingMultiplyShift (default) uses 32-bit keys and maps a key to
+key * capacity >> 32, every index is hit by the same number of keys give or take
+one. IndexMappingModulo uses keys of Config.KeySize() bits and maps a key to
+key % capacity, which hits low indexes up to twice as often if the capacity is
+not a power of two. It is used by filters which were created before
+IndexMappingMultiplyShift was added.
+*/
+type IndexMapping byte
+
+const (
+	IndexMappingMultiplyShift IndexMapping = iota
+	IndexMappingModulo
+)
+
+func (m IndexMapping) String() string {
+	switch m {
+	case IndexMappingMultiplyShift:
+		return "multiply-shift"
+	case IndexMappingModulo:
+		return "modulo"
+	}
+	return fmt.Sprintf("IndexMapping(%d)", byte(m))
+}
+
+/*
+WithMaxCapacity limits the capacity in bits of the Storage, New and Unmarshal
+return ErrStorageCapacityTooLarge for a larger Config before any memory is
+allocated. It is used when the Config or the encoded filter comes from
+untrusted input.
+*/
+func WithMaxCapacity(bits uint32) OptionFunc {
+	return func(o *Option) {
+		o.maxCapacity = bits
+	}
+}
+
+func (o Option) checkCapacity(capacity uint32) error {
+	if o.maxCapacity > 0 && capacity > o.maxCapacity {
+		return ErrStorageCapacityTooLarge
+	}
+	return nil
+}
+
+// WithIndexMapping sets the IndexMapping, the default is IndexMappingMultiplyShift.
+func WithIndexMapping(m IndexMapping) OptionFunc {
+	return func(o *Option) {
+		o.mapping = m
+	}
+}
+
+// index returns 0 if the capacity is 0, for example of a released Storage.
+func (m IndexMapping) index(key Key, capacity uint32) uint32 {
+	if m == IndexMappingModulo {
+		if capacity == 0 {
+			return 0
+		}
+		return uint32(key) % capacity
+	}
+	return uint32(uint64(key) * uint64(capacity) >> 32)
+}
+
+// indexMappingOf returns the IndexMapping of built-in filters, ok is false for
+// other implementations.
+func indexMappingOf(f ReadOnlyFilter) (m IndexMapping, ok bool) {
+	if b, ok := bloomFilterOf(f); ok {
+		return b.option.mapping, true
+	}
+	if d, ok := f.(*DurableBloomFilter); ok {
+		return d.filter.option.mapping, true
+	}
+	return IndexMappingMultiplyShift, false
+}
@@ -88,7 +88,7 @@ func TestNew_CallsStorageFactoryAndHasherFactory(t *testing.T) {
 	storage := &stubStorageFactory{storage: &mockStorage{}}
 	h := &stubHasherFactory{hasher: &mockHasher{}}
 
-	f, err := New(cf, WithStorage(storage), WithHasher(h))
+	f, err := New(cf, WithStorage(storage), WithHasher(h), WithIndexMapping(IndexMappingModulo))
 	if f == nil {
 		t.Errorf("expect filter is not nil but got nil")
 	}
@@ -107,6 +107,16 @@ func TestNew_CallsStorageFactoryAndHasherFactory(t *testing.T) {
 	}
 }
 
+func TestNew_MultiplyShiftUses32BitKeys(t *testing.T) {
+	cf := &dummyConfig{k: 10, capacity: 2000}
+	h := &stubHasherFactory{hasher: &mockHasher{}}
+
+	_, _ = New(cf, WithHasher(h))
+	if h.makeSize != 32 {
+		t.Errorf("expect %d but got %d", 32, h.makeSize)
+	}
+}
+
 type stubStorageFactory struct {
 	storage      Storage
 	err          error
@@ -118,6 +128,20 @@ func (s *stubStorageFactory) Make(capacity uint32) (Storage, error) {
 	return s.storage, s.err
 }
 
+func TestNew_WithMaxCapacity(t *testing.T) {
+	if _, err := New(WithCapacity(4097, 3), WithMaxCapacity(4096)); err != ErrStorageCapacityTooLarge {
+		t.Errorf("expected %v, got %v", ErrStorageCapacityTooLarge, err)
+	}
+	if _, err := New(WithCapacity(4096, 3), WithMaxCapacity(4096)); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+
+	data, _ := Marshal(Must(WithCapacity(4097, 3)))
+	if _, err := Unmarshal(data, WithMaxCapacity(4096)); err != ErrStorageCapacityTooLarge {
+		t.Errorf("expected %v, got %v", ErrStorageCapacityTooLarge, err)
+	}
+}
+
 func TestWithStorage(t *testing.T) {
 	opt := &Option{}
 	ds := &stubStorageFactory{}
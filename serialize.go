@@ -0,0 +1,330 @@
+package bf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+const serializationVersion byte = 2
+
+var serializationMagic = [4]byte{'G', 'O', 'B', 'F'}
+
+const (
+	hasherIDSHA byte = 1
+	hasherIDFNV byte = 2
+)
+
+const (
+	configModeCustom byte = iota
+	configModeAccuracy
+	configModeCapacity
+)
+
+const (
+	flagDistinctCount byte = 1 << iota
+	flagMultiplyShift
+)
+
+// serializedHeaderV1 is the header of version 1, version 2 appends Flags and
+// Distinct. Filters without flagMultiplyShift use IndexMappingModulo.
+type serializedHeaderV1 struct {
+	Magic     [4]byte
+	Version   byte
+	Hasher    byte
+	Encoding  byte
+	Mode      byte
+	K         byte
+	KeySize   byte
+	Capacity  uint32
+	Items     uint32
+	ErrorRate float64
+	Count     int64
+}
+
+type serializedHeader struct {
+	serializedHeaderV1
+	Flags    byte
+	Distinct int64
+}
+
+/*
+Marshal encodes the given filter including its Config, hasher and Storage
+data into a portable binary format. Only the built-in hashers WithSHA and
+WithFNV could be marshaled, any Storage is supported. Storage data is encoded
+by EncodingAuto unless another Encoding is given WithEncoding. The IndexMapping
+of filters which are not built-in must be given WithMarshalIndexMapping,
+otherwise ErrUnknownIndexMapping is returned.
+*/
+func Marshal(f ReadOnlyFilter, opts ...MarshalOptionFunc) ([]byte, error) {
+	if f == nil {
+		return nil, ErrNilBloomFilter
+	}
+
+	o := MarshalOption{encoding: EncodingAuto}
+	for _, opt := range opts {
+		if opt == nil {
+			return nil, ErrNilOptionFunc
+		}
+		opt(&o)
+	}
+
+	if c, ok := f.(*concurrentBloomFilter); ok {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		f = c.filter
+	}
+
+	cfg := f.Config()
+	if cfg == nil {
+		return nil, ErrNilConfig
+	}
+
+	hid, err := hasherID(f.Hasher())
+	if err != nil {
+		return nil, err
+	}
+
+	data := storageBytes(f.Storage())
+	if o.encoding == EncodingAuto {
+		o.encoding = chooseEncoding(data, f.Storage().Capacity())
+	}
+	payload, err := encodePayload(o.encoding, data, f.Storage().Capacity())
+	if err != nil {
+		return nil, err
+	}
+
+	h := serializedHeader{serializedHeaderV1: serializedHeaderV1{
+		Magic:    serializationMagic,
+		Version:  serializationVersion,
+		Hasher:   hid,
+		Encoding: byte(o.encoding),
+		Mode:     configModeCustom,
+		K:        cfg.NumberOfHashFunctions(),
+		KeySize:  keySizeOf(f, cfg),
+		Capacity: cfg.StorageCapacity(),
+		Count:    int64(f.AddCount()),
+	}}
+	if distinct := f.DistinctCount(); distinct >= 0 {
+		h.Flags |= flagDistinctCount
+		h.Distinct = int64(distinct)
+	}
+	m, ok := indexMappingOf(f)
+	if !ok {
+		if o.mapping == nil {
+			return nil, ErrUnknownIndexMapping
+		}
+		m = *o.mapping
+	}
+	if m == IndexMappingMultiplyShift {
+		h.Flags |= flagMultiplyShift
+	}
+	if c, ok := cfg.(config); ok {
+		switch c.mode {
+		case "accuracy":
+			h.Mode = configModeAccuracy
+			h.Items = c.n
+			h.ErrorRate = c.requestedE
+		case "capacity":
+			h.Mode = configModeCapacity
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, h); err != nil {
+		return nil, err
+	}
+	buf.Write(payload)
+	return buf.Bytes(), nil
+}
+
+/*
+Unmarshal decodes a BloomFilter which was encoded by Marshal. Options including
+WithStorage, WithConcurrency and WithDistinctCount could be used, the hasher
+and IndexMapping are always restored from the encoded data so WithHasher,
+WithSHA, WithFNV and WithIndexMapping are ignored. Data encoded before
+IndexMappingMultiplyShift was added is decoded WithIndexMapping(IndexMappingModulo). A filter encoded WithDistinctCount is decoded WithDistinctCount, the
+number of distinct items is estimated if it was not encoded.
+*/
+func Unmarshal(data []byte, opts ...OptionFunc) (BloomFilter, error) {
+	r := bytes.NewReader(data)
+	var h serializedHeader
+	if err := binary.Read(r, binary.LittleEndian, &h.serializedHeaderV1); err != nil {
+		return nil, ErrInvalidSerializedData
+	}
+
+	if h.Magic != serializationMagic {
+		return nil, ErrInvalidSerializedData
+	}
+	switch h.Version {
+	case 1:
+	case serializationVersion:
+		if err := binary.Read(r, binary.LittleEndian, &h.Flags); err != nil {
+			return nil, ErrInvalidSerializedData
+		}
+		if err := binary.Read(r, binary.LittleEndian, &h.Distinct); err != nil {
+			return nil, ErrInvalidSerializedData
+		}
+	default:
+		return nil, ErrUnsupportedSerializationVersion
+	}
+
+	hf, err := hasherFactoryFromID(h.Hasher)
+	if err != nil {
+		return nil, err
+	}
+
+	o := Option{
+		config:         configFromHeader(h.serializedHeaderV1),
+		storageFactory: memoryStorageFactory{},
+		distinctCount:  h.Flags&flagDistinctCount > 0,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			return nil, ErrNilOptionFunc
+		}
+		opt(&o)
+	}
+	if o.storageFactory == nil {
+		return nil, ErrNilStorageFactory
+	}
+	if err = o.checkCapacity(h.Capacity); err != nil {
+		return nil, err
+	}
+	o.hasherFactory = hf
+	o.mapping = IndexMappingModulo
+	if h.Flags&flagMultiplyShift > 0 {
+		o.mapping = IndexMappingMultiplyShift
+	}
+	o.config = configWithMapping(o.config, o.mapping)
+
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err = decodePayload(Encoding(h.Encoding), payload, h.Capacity)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := newBloomFilter(o)
+	if err != nil {
+		return nil, err
+	}
+	loadStorageBytes(f.storage, data)
+	f.count = int(h.Count)
+	if h.Flags&flagDistinctCount > 0 {
+		f.distinct = int(h.Distinct)
+	} else if o.distinctCount {
+		f.distinct = f.estimateDistinct()
+	}
+	return o.wrap(f), nil
+}
+
+func hasherID(h Hasher) (byte, error) {
+	switch h.(type) {
+	case *shaHasher:
+		return hasherIDSHA, nil
+	case *fnvHasher:
+		return hasherIDFNV, nil
+	}
+	return 0, ErrUnsupportedHasher
+}
+
+func hasherFactoryFromID(id byte) (HasherFactory, error) {
+	switch id {
+	case hasherIDSHA:
+		return shaHasherFactory{}, nil
+	case hasherIDFNV:
+		return fnvHasherFactory{}, nil
+	}
+	return nil, ErrUnsupportedHasher
+}
+
+func configFromHeader(h serializedHeaderV1) config {
+	if h.Mode == configModeAccuracy {
+		c := WithAccuracy(h.ErrorRate, h.Items).(config)
+		if c.storageCapacity == h.Capacity && c.k == h.K {
+			c.keySize = h.KeySize
+			return c
+		}
+	}
+
+	return config{
+		mode:            "capacity",
+		k:               h.K,
+		storageCapacity: h.Capacity,
+		keySize:         h.KeySize,
+	}
+}
+
+/*
+ExportStorage returns the raw bits of the given Storage in a layout which is the
+same on every platform: bit i is bit i % 8 of the byte i / 8, the built-in
+Storage is dumped as little-endian uint64 words. The result has
+(Capacity() + 7) / 8 bytes and could be loaded by ImportStorage on any platform.
+*/
+func ExportStorage(s Storage) []byte {
+	if s == nil {
+		return nil
+	}
+	return storageBytes(s)
+}
+
+/*
+ImportStorage loads data exported by ExportStorage into the given Storage, the
+Storage must have the same capacity and must not be read-only. Bits beyond the
+capacity are ignored.
+*/
+func ImportStorage(s Storage, data []byte) error {
+	if s == nil {
+		return ErrNilStorage
+	}
+	if b, ok := s.(*bitset); ok && b.readOnly {
+		return ErrReadOnlyStorage
+	}
+	if uint32(len(data)) != storageSizeInBytes(s.Capacity()) {
+		return ErrInvalidSerializedData
+	}
+	loadStorageBytes(s, data)
+	return nil
+}
+
+func storageSizeInBytes(capacity uint32) uint32 {
+	n := capacity / 8
+	if capacity%8 > 0 {
+		n++
+	}
+	return n
+}
+
+func storageBytes(s Storage) []byte {
+	if b, ok := s.(*bitset); ok {
+		return b.exportBytes()
+	}
+
+	capacity := s.Capacity()
+	result := make([]byte, storageSizeInBytes(capacity))
+	for i := uint32(0); i < capacity; i++ {
+		if s.Get(i) {
+			result[i/8] |= 1 << (i % 8)
+		}
+	}
+	return result
+}
+
+func loadStorageBytes(s Storage, data []byte) {
+	if b, ok := s.(*bitset); ok {
+		b.importBytes(data)
+		return
+	}
+
+	capacity := s.Capacity()
+	for i := uint32(0); i < capacity; i++ {
+		if data[i/8]&(1<<(i%8)) > 0 {
+			s.Set(i)
+		} else {
+			s.Clear(i)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package bf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMarshal_ReturnsErrIfBloomFilterIsNil(t *testing.T) {
+	_, err := Marshal(nil)
+	if !errors.Is(err, ErrNilBloomFilter) {
+		t.Errorf("expected ErrNilBloomFilter, got %v", err)
+	}
+}
+
+func TestMarshal_ReturnsErrIfConfigIsNil(t *testing.T) {
+	f := &bloomFilter{hasher: &shaHasher{}, storage: &mockStorage{capacity: 1}}
+	_, err := Marshal(f)
+	if !errors.Is(err, ErrNilConfig) {
+		t.Errorf("expected ErrNilConfig, got %v", err)
+	}
+}
+
+func TestMarshal_ReturnsErrIfHasherIsNotSupported(t *testing.T) {
+	f := Must(WithCapacity(1000, 3), WithHasher(&stubHasherFactory{hasher: &mockHasher{}}))
+	_, err := Marshal(f)
+	if !errors.Is(err, ErrUnsupportedHasher) {
+		t.Errorf("expected ErrUnsupportedHasher, got %v", err)
+	}
+}
+
+func TestMarshal_Unmarshal(t *testing.T) {
+	cases := []struct {
+		name   string
+		config Config
+		opt    OptionFunc
+	}{
+		{name: "accuracy with SHA", config: WithAccuracy(0.01, 1000), opt: WithSHA()},
+		{name: "accuracy with FNV", config: WithAccuracy(0.001, 5000), opt: WithFNV()},
+		{name: "capacity with SHA", config: WithCapacity(1021, 4), opt: WithSHA()},
+		{name: "capacity with FNV", config: WithCapacity(64, 2), opt: WithFNV()},
+		{name: "custom config", config: &dummyConfig{k: 3, capacity: 2000}, opt: WithSHA()},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := Must(tc.config, tc.opt)
+			for i := 0; i < 50; i++ {
+				f.Add([]byte{byte(i), 'x'})
+			}
+
+			data, err := Marshal(f)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			r, err := Unmarshal(data)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+
+			if !r.Hasher().Equals(f.Hasher()) {
+				t.Errorf("expected the same hasher")
+			}
+			if !r.Storage().Equals(f.Storage()) {
+				t.Errorf("expected the same storage")
+			}
+			if r.Count() != f.Count() {
+				t.Errorf("expected count %v, got %v", f.Count(), r.Count())
+			}
+			if r.Config().KeySize() != keySizeOf(f, f.Config()) {
+				t.Errorf("expected key size %v, got %v", keySizeOf(f, f.Config()), r.Config().KeySize())
+			}
+			if _, ok := tc.config.(config); ok && r.Config().Info() != f.Config().Info() {
+				t.Errorf("expected info %v, got %v", f.Config().Info(), r.Config().Info())
+			}
+
+			a, b := f.Storage().(*bitset), r.Storage().(*bitset)
+			if !isArrayEquals(a.data, b.data) {
+				t.Errorf("expected %v, got %v", a.data, b.data)
+			}
+			for i := 0; i < 50; i++ {
+				if !r.Exists([]byte{byte(i), 'x'}) {
+					t.Errorf("expected item %v exists after Unmarshal", i)
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshal_WithStorage(t *testing.T) {
+	f := Must(WithCapacity(10, 2))
+	f.Storage().Set(1)
+	f.Storage().Set(8)
+	data, _ := Marshal(f)
+
+	storage := &mockStorage{capacity: 10}
+	_, err := Unmarshal(data, WithStorage(&stubStorageFactory{storage: storage}))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	storage.assertSetCalledWith(t, []uint32{1, 8})
+	storage.assertClearCalledWith(t, []uint32{0, 2, 3, 4, 5, 6, 7, 9})
+}
+
+func TestUnmarshal_ReturnsErrIfDataIsInvalid(t *testing.T) {
+	data, _ := Marshal(Must(WithCapacity(100, 2)))
+
+	badMagic := append([]byte{}, data...)
+	badMagic[0] = 'X'
+	badVersion := append([]byte{}, data...)
+	badVersion[4] = 99
+	badHasher := append([]byte{}, data...)
+	badHasher[5] = 99
+
+	cases := []struct {
+		name     string
+		data     []byte
+		opts     []OptionFunc
+		expected error
+	}{
+		{name: "empty", data: nil, expected: ErrInvalidSerializedData},
+		{name: "truncated header", data: data[:10], expected: ErrInvalidSerializedData},
+		{name: "truncated payload", data: data[:len(data)-1], expected: ErrInvalidSerializedData},
+		{name: "magic", data: badMagic, expected: ErrInvalidSerializedData},
+		{name: "version", data: badVersion, expected: ErrUnsupportedSerializationVersion},
+		{name: "hasher", data: badHasher, expected: ErrUnsupportedHasher},
+		{name: "nil option", data: data, opts: []OptionFunc{nil}, expected: ErrNilOptionFunc},
+		{name: "nil storage factory", data: data, opts: []OptionFunc{WithStorage(nil)}, expected: ErrNilStorageFactory},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := Unmarshal(tc.data, tc.opts...)
+			if f != nil {
+				t.Errorf("expected nil, got %v", f)
+			}
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestMarshal_Unmarshal_DistinctCount(t *testing.T) {
+	f := Must(WithCapacity(4096, 3), WithDistinctCount())
+	f.Add([]byte("a"))
+	f.Add([]byte("a"))
+	f.Add([]byte("b"))
+
+	data, _ := Marshal(f)
+	r, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if r.AddCount() != 3 || r.DistinctCount() != 2 || r.Count() != 2 {
+		t.Errorf("expected counters 3, 2, 2, got %v, %v, %v", r.AddCount(), r.DistinctCount(), r.Count())
+	}
+
+	r.Add([]byte("c"))
+	if r.DistinctCount() != 3 {
+		t.Errorf("expected distinct count is tracked after Unmarshal, got %v", r.DistinctCount())
+	}
+}
+
+func TestUnmarshal_Version1(t *testing.T) {
+	f := Must(WithCapacity(4096, 3))
+	for i := 0; i < 100; i++ {
+		f.Add([]byte{byte(i), 'x'})
+	}
+	data, _ := Marshal(f, WithEncoding(EncodingRaw))
+
+	// a version 1 header has no Flags and Distinct
+	v1 := append([]byte{}, data[:binary.Size(serializedHeaderV1{})]...)
+	v1[4] = 1
+	v1 = append(v1, data[binary.Size(serializedHeader{}):]...)
+
+	r, err := Unmarshal(v1)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if r.(*bloomFilter).option.mapping != IndexMappingModulo {
+		t.Errorf("expected version 1 is decoded WithIndexMapping(IndexMappingModulo)")
+	}
+	if r.Count() != 100 || r.DistinctCount() != -1 {
+		t.Errorf("expected count 100 and no distinct count, got %v, %v", r.Count(), r.DistinctCount())
+	}
+
+	r, _ = Unmarshal(v1, WithDistinctCount())
+	if r.AddCount() != 100 || math.Abs(float64(r.DistinctCount()-100)) > 5 {
+		t.Errorf("expected estimated distinct count about 100, got %v", r.DistinctCount())
+	}
+}
+
+func TestMarshal_Unmarshal_IndexMapping(t *testing.T) {
+	for _, m := range []IndexMapping{IndexMappingMultiplyShift, IndexMappingModulo} {
+		f := Must(WithCapacity(5000, 3), WithIndexMapping(m))
+		f.Add([]byte("item"))
+		data, _ := Marshal(f)
+
+		// the encoded mapping wins over the given option
+		r, err := Unmarshal(data, WithIndexMapping(IndexMapping(7)))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if r.(*bloomFilter).option.mapping != m || !r.Exists([]byte("item")) {
+			t.Errorf("expected %v is restored", m)
+		}
+	}
+}
+
+func TestMarshal_KeySizeIsTheSizeOfKeysOfTheHasher(t *testing.T) {
+	cases := []struct {
+		mapping  IndexMapping
+		expected byte
+	}{
+		{mapping: IndexMappingMultiplyShift, expected: 32},
+		{mapping: IndexMappingModulo, expected: 13},
+	}
+
+	for _, tc := range cases {
+		f := Must(WithCapacity(5000, 3), WithIndexMapping(tc.mapping))
+		if f.Config().KeySize() != tc.expected || !strings.Contains(f.Config().Info(), fmt.Sprintf("function: %v", tc.expected)) {
+			t.Errorf("expected key size %v with %v, got %v", tc.expected, tc.mapping, f.Config().KeySize())
+		}
+
+		data, _ := Marshal(f)
+		if data[9] != tc.expected {
+			t.Errorf("expected key size %v is encoded, got %v", tc.expected, data[9])
+		}
+		r, _ := Unmarshal(data)
+		if r.Config().KeySize() != tc.expected {
+			t.Errorf("expected key size %v, got %v", tc.expected, r.Config().KeySize())
+		}
+	}
+}
+
+func TestMarshal_ReturnsErrIfIndexMappingIsUnknown(t *testing.T) {
+	f := wrappedBloomFilter{Must(WithCapacity(5000, 3), WithIndexMapping(IndexMappingModulo))}
+	f.Add([]byte("item"))
+
+	if _, err := Marshal(f); err != ErrUnknownIndexMapping {
+		t.Errorf("expected %v, got %v", ErrUnknownIndexMapping, err)
+	}
+
+	data, err := Marshal(f, WithMarshalIndexMapping(IndexMappingModulo))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	r, _ := Unmarshal(data)
+	if r.(*bloomFilter).option.mapping != IndexMappingModulo || !r.Exists([]byte("item")) {
+		t.Errorf("expected the given mapping is encoded")
+	}
+}
+
+// dumpWords32 and dumpWords64 lay bits out as a bitset of 32-bit or 64-bit
+// words in little-endian order would, like on ARMv7 and amd64.
+func dumpWords32(capacity uint32, bits []uint32) []byte {
+	words := make([]uint32, (capacity+31)/32)
+	for _, i := range bits {
+		words[i/32] |= 1 << (i % 32)
+	}
+	result := make([]byte, len(words)*4)
+	for i, w := range words {
+		binary.LittleEndian.PutUint32(result[i*4:], w)
+	}
+	return result[:storageSizeInBytes(capacity)]
+}
+
+func dumpWords64(capacity uint32, bits []uint32) []byte {
+	words := make([]uint64, (capacity+63)/64)
+	for _, i := range bits {
+		words[i/64] |= 1 << (i % 64)
+	}
+	result := make([]byte, len(words)*8)
+	for i, w := range words {
+		binary.LittleEndian.PutUint64(result[i*8:], w)
+	}
+	return result[:storageSizeInBytes(capacity)]
+}
+
+func TestExportStorage_ImportStorage(t *testing.T) {
+	cases := []struct {
+		name     string
+		capacity uint32
+		bits     []uint32
+	}{
+		{name: "less than a word", capacity: 20, bits: []uint32{0, 7, 8, 19}},
+		{name: "32-bit word boundary", capacity: 64, bits: []uint32{31, 32, 63}},
+		{name: "64-bit word boundary", capacity: 130, bits: []uint32{0, 63, 64, 95, 96, 127, 128, 129}},
+		{name: "odd capacity", capacity: 1001, bits: []uint32{1, 100, 333, 511, 512, 999, 1000}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a32, a64 := dumpWords32(tc.capacity, tc.bits), dumpWords64(tc.capacity, tc.bits)
+			if !isArrayEquals(a32, a64) {
+				t.Fatalf("expected 32-bit and 64-bit layouts are the same, got %v and %v", a32, a64)
+			}
+
+			for _, sf := range []StorageFactory{memoryStorageFactory{}, sliceStorageFactory{}} {
+				s, _ := sf.Make(tc.capacity)
+				for _, i := range tc.bits {
+					s.Set(i)
+				}
+				if data := ExportStorage(s); !isArrayEquals(data, a32) {
+					t.Errorf("%T: expected %v, got %v", s, a32, data)
+				}
+
+				r, _ := sf.Make(tc.capacity)
+				r.Set(tc.capacity / 2)
+				if err := ImportStorage(r, a32); err != nil {
+					t.Fatalf("expected nil, got %v", err)
+				}
+				for i := uint32(0); i < tc.capacity; i++ {
+					if r.Get(i) != s.Get(i) {
+						t.Errorf("%T: index %v expected %v, got %v", r, i, s.Get(i), r.Get(i))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestExportStorage_Layout(t *testing.T) {
+	s, _ := memoryStorageFactory{}.Make(72)
+	s.Set(0)
+	s.Set(9)
+	s.Set(63)
+	s.Set(71)
+
+	expected := []byte{0b1, 0b10, 0, 0, 0, 0, 0, 0b10000000, 0b10000000}
+	if data := ExportStorage(s); !isArrayEquals(data, expected) {
+		t.Errorf("expected %v, got %v", expected, data)
+	}
+}
+
+func TestImportStorage_ReturnsErr(t *testing.T) {
+	if err := ImportStorage(nil, []byte{0}); !errors.Is(err, ErrNilStorage) {
+		t.Errorf("expected ErrNilStorage, got %v", err)
+	}
+
+	s, _ := memoryStorageFactory{}.Make(72)
+	if err := ImportStorage(s, make([]byte, 8)); !errors.Is(err, ErrInvalidSerializedData) {
+		t.Errorf("expected ErrInvalidSerializedData, got %v", err)
+	}
+}
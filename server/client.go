@@ -0,0 +1,332 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/toniphan21/go-bf"
+)
+
+/*
+Client implements bf.BloomFilter for a named filter on a Server. Methods of
+bf.BloomFilter which cannot return an error record it, the last error could be
+retrieved by Err. Exists and TestAndAdd return true if there is an error, so
+an item is not taken as new while the Server is unreachable. Storage, Hasher,
+Config and Clone download a snapshot and return a local copy.
+*/
+type Client struct {
+	baseURL string
+	name    string
+	http    *http.Client
+
+	mu  sync.Mutex
+	err error
+}
+
+var _ bf.BloomFilter = (*Client)(nil)
+
+func NewClient(baseURL, name string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), name: name, http: httpClient}
+}
+
+// Err returns the last error of methods which cannot return an error.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+func (c *Client) Create(req CreateRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(http.MethodPut, "", "application/json", body)
+	return err
+}
+
+func (c *Client) Delete() error {
+	_, err := c.do(http.MethodDelete, "", "", nil)
+	return err
+}
+
+func (c *Client) Info() (Info, error) {
+	var info Info
+	data, err := c.do(http.MethodGet, "", "", nil)
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(data, &info)
+	return info, err
+}
+
+func (c *Client) AddBatch(items [][]byte) error {
+	body, err := json.Marshal(BatchRequest{Items: items})
+	if err != nil {
+		return err
+	}
+	_, err = c.do(http.MethodPost, "add-batch", "application/json", body)
+	return err
+}
+
+func (c *Client) ExistsBatch(items [][]byte) ([]bool, error) {
+	body, err := json.Marshal(BatchRequest{Items: items})
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.do(http.MethodPost, "exists-batch", "application/json", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp BatchExistsResponse
+	if err = json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Snapshot downloads the filter and returns a local copy.
+func (c *Client) Snapshot() (bf.BloomFilter, error) {
+	data, err := c.do(http.MethodGet, "snapshot", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return bf.Unmarshal(data)
+}
+
+// Restore creates or replaces the filter on the Server by the given filter.
+func (c *Client) Restore(f bf.BloomFilter) error {
+	data, err := c.marshal(f)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(http.MethodPut, "snapshot", "application/octet-stream", data)
+	return err
+}
+
+func (c *Client) Add(item []byte) {
+	_, err := c.do(http.MethodPost, "add", "application/octet-stream", item)
+	c.record(err)
+}
+
+// Exists returns true if there is an error like a filter may contain any item,
+// check Err to tell it from an item which exists.
+func (c *Client) Exists(item []byte) bool {
+	data, err := c.do(http.MethodPost, "exists", "application/octet-stream", item)
+	if c.record(err) {
+		return true
+	}
+
+	var resp ExistsResponse
+	if c.record(json.Unmarshal(data, &resp)) {
+		return true
+	}
+	return resp.Exists
+}
+
+// TestAndAdd is atomic on the Server, true is returned if there is an error
+// like Exists.
+func (c *Client) TestAndAdd(item []byte) bool {
+	data, err := c.do(http.MethodPost, "test-and-add", "application/octet-stream", item)
+	if c.record(err) {
+		return true
+	}
+
+	var resp ExistsResponse
+	if c.record(json.Unmarshal(data, &resp)) {
+		return true
+	}
+	return resp.Exists
+}
+
+// Keys returns a handle which is not hashed, items are hashed by the server.
+func (c *Client) Keys(item []byte) bf.KeyHandle {
+	return bf.NewKeyHandle(item)
+}
+
+// AddKeys adds the item of the handle like Add, it returns the error of the
+// request instead of recording it.
+func (c *Client) AddKeys(h bf.KeyHandle) error {
+	_, err := c.do(http.MethodPost, "add", "application/octet-stream", h.Item())
+	return err
+}
+
+// ExistsKeys checks the item of the handle like Exists, it returns the error
+// of the request instead of recording it.
+func (c *Client) ExistsKeys(h bf.KeyHandle) (bool, error) {
+	data, err := c.do(http.MethodPost, "exists", "application/octet-stream", h.Item())
+	if err != nil {
+		return false, err
+	}
+
+	var resp ExistsResponse
+	if err = json.Unmarshal(data, &resp); err != nil {
+		return false, err
+	}
+	return resp.Exists, nil
+}
+
+func (c *Client) Count() int {
+	info, err := c.Info()
+	if c.record(err) {
+		return -1
+	}
+	return info.Count
+}
+
+func (c *Client) AddCount() int {
+	info, err := c.Info()
+	if c.record(err) {
+		return -1
+	}
+	return info.AddCount
+}
+
+func (c *Client) DistinctCount() int {
+	info, err := c.Info()
+	if c.record(err) {
+		return -1
+	}
+	return info.DistinctCount
+}
+
+func (c *Client) Storage() bf.Storage {
+	f := c.snapshot()
+	if f == nil {
+		return nil
+	}
+	return f.Storage()
+}
+
+func (c *Client) Hasher() bf.Hasher {
+	f := c.snapshot()
+	if f == nil {
+		return nil
+	}
+	return f.Hasher()
+}
+
+func (c *Client) Config() bf.Config {
+	f := c.snapshot()
+	if f == nil {
+		return nil
+	}
+	return f.Config()
+}
+
+func (c *Client) Intersect(other bf.ReadOnlyFilter) error {
+	return c.merge("intersect", other)
+}
+
+func (c *Client) Union(other bf.ReadOnlyFilter) error {
+	return c.merge("union", other)
+}
+
+func (c *Client) Clone() (bf.BloomFilter, error) {
+	return c.Snapshot()
+}
+
+func (c *Client) Reset() {
+	_, err := c.do(http.MethodPost, "reset", "", nil)
+	c.record(err)
+}
+
+func (c *Client) CopyFrom(other bf.ReadOnlyFilter) error {
+	return c.merge("copy", other)
+}
+
+func (c *Client) merge(action string, other bf.ReadOnlyFilter) error {
+	if other == nil {
+		return bf.ErrNilBloomFilter
+	}
+
+	data, err := c.marshal(other)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(http.MethodPost, action, "application/octet-stream", data)
+	return err
+}
+
+func (c *Client) marshal(f bf.ReadOnlyFilter) ([]byte, error) {
+	if o, ok := f.(*Client); ok {
+		return o.do(http.MethodGet, "snapshot", "", nil)
+	}
+	return bf.Marshal(f)
+}
+
+func (c *Client) snapshot() bf.BloomFilter {
+	f, err := c.Snapshot()
+	if c.record(err) {
+		return nil
+	}
+	return f
+}
+
+func (c *Client) record(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.err = err
+	return true
+}
+
+func (c *Client) do(method, action, contentType string, body []byte) ([]byte, error) {
+	u := c.baseURL + filtersPath + "/" + url.PathEscape(c.name)
+	if action != "" {
+		u += "/" + action
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var e ErrorResponse
+		if json.Unmarshal(data, &e) != nil || e.Error == "" {
+			e.Error = http.StatusText(resp.StatusCode)
+		}
+		return nil, &StatusError{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+	return data, nil
+}
+
+// StatusError is returned by Client if the Server responds an error status.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("server responded %d: %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/toniphan21/go-bf"
+)
+
+func newTestClient(t *testing.T, name string) (*Client, *Server) {
+	s := New()
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return NewClient(ts.URL+"/", name, ts.Client()), s
+}
+
+func TestClient_ImplementsBloomFilter(t *testing.T) {
+	c, _ := newTestClient(t, "a/b")
+	if err := c.Create(CreateRequest{Capacity: 4096, HashFunctions: 3, Hasher: "fnv"}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	var f bf.BloomFilter = c
+	f.Add([]byte("hello"))
+	if !f.Exists([]byte("hello")) || f.Exists([]byte("other")) {
+		t.Errorf("unexpected exists result")
+	}
+	if f.Count() != 1 {
+		t.Errorf("expected 1, got %v", f.Count())
+	}
+	if f.AddCount() != 1 || f.DistinctCount() != -1 {
+		t.Errorf("expected counters 1, -1, got %v, %v", f.AddCount(), f.DistinctCount())
+	}
+	if f.Storage().Capacity() != 4096 || f.Config().NumberOfHashFunctions() != 3 {
+		t.Errorf("unexpected storage or config")
+	}
+	if !f.Hasher().Equals(bf.Must(bf.WithCapacity(4096, 3), bf.WithFNV()).Hasher()) {
+		t.Errorf("expected FNV hasher")
+	}
+
+	cloned, err := f.Clone()
+	if err != nil || !cloned.Exists([]byte("hello")) {
+		t.Errorf("expected cloned filter contains hello, got %v", err)
+	}
+	if c.Err() != nil {
+		t.Errorf("expected nil, got %v", c.Err())
+	}
+}
+
+func TestClient_TestAndAdd(t *testing.T) {
+	c, _ := newTestClient(t, "a")
+	_ = c.Create(CreateRequest{Capacity: 4096, HashFunctions: 3})
+
+	c.Add([]byte("hello"))
+	if !c.TestAndAdd([]byte("hello")) || c.TestAndAdd([]byte("new")) || !c.Exists([]byte("new")) {
+		t.Errorf("unexpected test and add result")
+	}
+	if c.Count() != 3 {
+		t.Errorf("expected 3, got %v", c.Count())
+	}
+	if c.Err() != nil {
+		t.Errorf("expected nil, got %v", c.Err())
+	}
+}
+
+func TestClient_Keys(t *testing.T) {
+	c, _ := newTestClient(t, "a")
+	_ = c.Create(CreateRequest{Capacity: 4096, HashFunctions: 3})
+
+	local := bf.Must(bf.WithCapacity(4096, 3))
+	h := c.Keys([]byte("hello"))
+	if h.Hashed() {
+		t.Errorf("expected the handle is hashed by the server")
+	}
+	if err := c.AddKeys(h); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if err := local.AddKeys(h); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if ok, err := c.ExistsKeys(local.Keys([]byte("hello"))); !ok || err != nil {
+		t.Errorf("expected true, got %v, %v", ok, err)
+	}
+	if ok, _ := c.ExistsKeys(h); !ok || !local.Exists([]byte("hello")) {
+		t.Errorf("expected hello exists")
+	}
+
+	missing := NewClient(c.baseURL, "missing", nil)
+	if _, err := missing.ExistsKeys(h); err == nil {
+		t.Errorf("expected error")
+	}
+	if err := missing.AddKeys(h); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestClient_BatchUnionIntersectAndRestore(t *testing.T) {
+	c, s := newTestClient(t, "a")
+	_ = c.Create(CreateRequest{Capacity: 4096, HashFunctions: 3})
+	if err := c.AddBatch([][]byte{[]byte("a"), []byte("shared")}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	other := NewClient(c.baseURL, "b", nil)
+	_ = other.Create(CreateRequest{Capacity: 4096, HashFunctions: 3})
+	_ = other.AddBatch([][]byte{[]byte("b"), []byte("shared")})
+
+	local := bf.Must(bf.WithCapacity(4096, 3))
+	local.Add([]byte("c"))
+
+	if err := c.Union(other); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if err := c.Union(local); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	results, err := c.ExistsBatch([][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")})
+	if err != nil || !isResultsEqual(results, []bool{true, true, true, false}) {
+		t.Errorf("unexpected results %v, %v", results, err)
+	}
+
+	if err = c.Intersect(other); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	results, _ = c.ExistsBatch([][]byte{[]byte("a"), []byte("b"), []byte("shared")})
+	if !isResultsEqual(results, []bool{false, true, true}) {
+		t.Errorf("unexpected results %v", results)
+	}
+
+	if err = c.Restore(local); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	f, _ := s.Filter("a")
+	if f.Exists([]byte("b")) || !f.Exists([]byte("c")) {
+		t.Errorf("expected filter is replaced")
+	}
+
+	if err = c.Union(nil); !errors.Is(err, bf.ErrNilBloomFilter) {
+		t.Errorf("expected ErrNilBloomFilter, got %v", err)
+	}
+}
+
+func TestClient_ResetAndCopyFrom(t *testing.T) {
+	c, s := newTestClient(t, "a")
+	_ = c.Create(CreateRequest{Capacity: 4096, HashFunctions: 3})
+	c.Add([]byte("a"))
+
+	local := bf.Must(bf.WithCapacity(4096, 3))
+	local.Add([]byte("b"))
+	if err := c.CopyFrom(local); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	f, _ := s.Filter("a")
+	if f.Exists([]byte("a")) || !f.Exists([]byte("b")) || f.Count() != 1 {
+		t.Errorf("expected filter is a copy")
+	}
+	if err := c.CopyFrom(bf.Must(bf.WithCapacity(1024, 3))); err == nil {
+		t.Errorf("expected error")
+	}
+
+	c.Reset()
+	if c.Exists([]byte("b")) || c.Count() != 0 || c.Err() != nil {
+		t.Errorf("expected filter is reset, got %v", c.Err())
+	}
+
+	missing := NewClient(c.baseURL, "missing", nil)
+	missing.Reset()
+	if missing.Err() == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	c, _ := newTestClient(t, "missing")
+
+	if !c.Exists([]byte("a")) || !c.TestAndAdd([]byte("a")) {
+		t.Errorf("expected true on error")
+	}
+	var se *StatusError
+	if !errors.As(c.Err(), &se) || se.StatusCode != http.StatusNotFound || se.Message != ErrFilterNotFound.Error() {
+		t.Errorf("expected not found StatusError, got %v", c.Err())
+	}
+
+	if c.Count() != -1 || c.Storage() != nil || c.Hasher() != nil || c.Config() != nil {
+		t.Errorf("expected zero values on error")
+	}
+	if _, err := c.Clone(); err == nil {
+		t.Errorf("expected error")
+	}
+	if err := c.Delete(); err == nil {
+		t.Errorf("expected error")
+	}
+
+	_ = c.Create(CreateRequest{Capacity: 4096, HashFunctions: 3})
+	err := c.Union(bf.Must(bf.WithCapacity(1024, 3)))
+	if !errors.As(err, &se) || se.StatusCode != http.StatusConflict {
+		t.Errorf("expected conflict StatusError, got %v", err)
+	}
+}
+
+func isResultsEqual(a, b []bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
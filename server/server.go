@@ -0,0 +1,445 @@
+/*
+Package server exposes named Bloom Filters over HTTP so several services could
+share a filter without embedding their own. Filters are created WithConcurrency
+and snapshots use the bf.Marshal format.
+
+Routes:
+
+	GET    /filters                      list filter names
+	PUT    /filters/{name}               create a filter from CreateRequest
+	GET    /filters/{name}               get Info of a filter
+	DELETE /filters/{name}               delete a filter
+	POST   /filters/{name}/add           add the request body as an item
+	POST   /filters/{name}/exists        check the request body as an item
+	POST   /filters/{name}/test-and-add  check then add the request body as an item
+	POST   /filters/{name}/add-batch     add items of BatchRequest
+	POST   /filters/{name}/exists-batch  check items of BatchRequest
+	POST   /filters/{name}/union         union with the snapshot in request body
+	POST   /filters/{name}/intersect     intersect with the snapshot in request body
+	POST   /filters/{name}/copy          copy the snapshot in request body into the filter
+	POST   /filters/{name}/reset         clear the filter
+	GET    /filters/{name}/snapshot      download the snapshot
+	PUT    /filters/{name}/snapshot      create or replace a filter by the snapshot
+
+Request bodies larger than WithMaxBodySize and filters larger than
+WithMaxCapacity are rejected with 413 Request Entity Too Large.
+*/
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/toniphan21/go-bf"
+)
+
+const filtersPath = "/filters"
+
+const (
+	// DefaultMaxBodySize fits a raw snapshot of a filter of DefaultMaxCapacity.
+	DefaultMaxBodySize int64 = 128 << 20
+
+	// DefaultMaxCapacity is 512 Mbit, a filter of 64 MB.
+	DefaultMaxCapacity uint32 = 1 << 29
+)
+
+var ErrFilterNotFound = errors.New("filter not found")
+var ErrFilterExists = errors.New("filter already exists")
+var ErrInvalidFilterName = errors.New("invalid filter name")
+var ErrUnknownHasher = errors.New("unknown hasher")
+
+type CreateRequest struct {
+	ErrorRate     float64 `json:"error_rate,omitempty"`
+	Items         uint32  `json:"items,omitempty"`
+	Capacity      uint32  `json:"capacity,omitempty"`
+	HashFunctions byte    `json:"hash_functions,omitempty"`
+	Hasher        string  `json:"hasher,omitempty"`
+	DistinctCount bool    `json:"distinct_count,omitempty"`
+}
+
+type Info struct {
+	Name          string `json:"name"`
+	Count         int    `json:"count"`
+	AddCount      int    `json:"add_count"`
+	DistinctCount int    `json:"distinct_count"`
+	Capacity      uint32 `json:"capacity"`
+	HashFunctions byte   `json:"hash_functions"`
+	KeySize       byte   `json:"key_size"`
+	Config        string `json:"config"`
+}
+
+type BatchRequest struct {
+	Items [][]byte `json:"items"`
+}
+
+type ExistsResponse struct {
+	Exists bool `json:"exists"`
+}
+
+type BatchExistsResponse struct {
+	Results []bool `json:"results"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type Server struct {
+	mu          sync.RWMutex
+	filters     map[string]bf.BloomFilter
+	maxBodySize int64
+	maxCapacity uint32
+}
+
+type Option func(s *Server)
+
+// WithMaxBodySize limits the size in bytes of request bodies, default is
+// DefaultMaxBodySize.
+func WithMaxBodySize(bytes int64) Option {
+	return func(s *Server) {
+		s.maxBodySize = bytes
+	}
+}
+
+// WithMaxCapacity limits the capacity in bits of created and uploaded filters,
+// default is DefaultMaxCapacity.
+func WithMaxCapacity(bits uint32) Option {
+	return func(s *Server) {
+		s.maxCapacity = bits
+	}
+}
+
+func New(opts ...Option) *Server {
+	s := &Server{
+		filters:     make(map[string]bf.BloomFilter),
+		maxBodySize: DefaultMaxBodySize,
+		maxCapacity: DefaultMaxCapacity,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Filter returns the filter with the given name, it is safe for concurrent use.
+func (s *Server) Filter(name string) (bf.BloomFilter, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, ok := s.filters[name]
+	return f, ok
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBodySize)
+
+	path := strings.Trim(r.URL.EscapedPath(), "/")
+	parts := strings.Split(path, "/")
+	if parts[0] != strings.Trim(filtersPath, "/") || len(parts) > 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.list(w)
+		return
+	}
+
+	name, err := url.PathUnescape(parts[1])
+	if err != nil || name == "" {
+		writeError(w, ErrInvalidFilterName)
+		return
+	}
+
+	action := ""
+	if len(parts) == 3 {
+		action = parts[2]
+	}
+
+	switch action {
+	case "":
+		switch r.Method {
+		case http.MethodPut:
+			s.create(w, r, name)
+		case http.MethodGet:
+			s.info(w, name)
+		case http.MethodDelete:
+			s.delete(w, name)
+		default:
+			writeMethodNotAllowed(w, http.MethodPut, http.MethodGet, http.MethodDelete)
+		}
+	case "snapshot":
+		switch r.Method {
+		case http.MethodGet:
+			s.download(w, name)
+		case http.MethodPut:
+			s.upload(w, r, name)
+		default:
+			writeMethodNotAllowed(w, http.MethodGet, http.MethodPut)
+		}
+	case "add", "exists", "test-and-add", "add-batch", "exists-batch", "union", "intersect", "copy", "reset":
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, http.MethodPost)
+			return
+		}
+		f, ok := s.Filter(name)
+		if !ok {
+			writeError(w, ErrFilterNotFound)
+			return
+		}
+		s.handleFilterAction(w, r, f, action)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.filters))
+	for name := range s.filters {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, names)
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request, name string) {
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, badRequest{err})
+		return
+	}
+
+	f, err := newFilter(req, s.maxCapacity)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.filters[name]; ok {
+		writeError(w, ErrFilterExists)
+		return
+	}
+	s.filters[name] = f
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) info(w http.ResponseWriter, name string) {
+	f, ok := s.Filter(name)
+	if !ok {
+		writeError(w, ErrFilterNotFound)
+		return
+	}
+
+	cfg := f.Config()
+	writeJSON(w, http.StatusOK, Info{
+		Name:          name,
+		Count:         f.Count(),
+		AddCount:      f.AddCount(),
+		DistinctCount: f.DistinctCount(),
+		Capacity:      cfg.StorageCapacity(),
+		HashFunctions: cfg.NumberOfHashFunctions(),
+		KeySize:       cfg.KeySize(),
+		Config:        cfg.Info(),
+	})
+}
+
+func (s *Server) delete(w http.ResponseWriter, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.filters[name]; !ok {
+		writeError(w, ErrFilterNotFound)
+		return
+	}
+	delete(s.filters, name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) download(w http.ResponseWriter, name string) {
+	f, ok := s.Filter(name)
+	if !ok {
+		writeError(w, ErrFilterNotFound)
+		return
+	}
+
+	data, err := bf.Marshal(f)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(data)
+}
+
+func (s *Server) upload(w http.ResponseWriter, r *http.Request, name string) {
+	f, err := s.readSnapshot(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.filters[name] = f
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleFilterAction(w http.ResponseWriter, r *http.Request, f bf.BloomFilter, action string) {
+	switch action {
+	case "add", "exists", "test-and-add":
+		item, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, badRequest{err})
+			return
+		}
+		switch action {
+		case "add":
+			f.Add(item)
+			w.WriteHeader(http.StatusNoContent)
+		case "exists":
+			writeJSON(w, http.StatusOK, ExistsResponse{Exists: f.Exists(item)})
+		default:
+			writeJSON(w, http.StatusOK, ExistsResponse{Exists: f.TestAndAdd(item)})
+		}
+
+	case "add-batch", "exists-batch":
+		var req BatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, badRequest{err})
+			return
+		}
+		if action == "add-batch" {
+			for _, item := range req.Items {
+				f.Add(item)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		results := make([]bool, len(req.Items))
+		for i, item := range req.Items {
+			results[i] = f.Exists(item)
+		}
+		writeJSON(w, http.StatusOK, BatchExistsResponse{Results: results})
+
+	case "reset":
+		f.Reset()
+		w.WriteHeader(http.StatusNoContent)
+
+	case "union", "intersect", "copy":
+		other, err := s.readSnapshot(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		switch action {
+		case "union":
+			err = f.Union(other)
+		case "intersect":
+			err = f.Intersect(other)
+		default:
+			err = f.CopyFrom(other)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newFilter(req CreateRequest, maxCapacity uint32) (bf.BloomFilter, error) {
+	var opt bf.OptionFunc
+	switch req.Hasher {
+	case "", "sha":
+		opt = bf.WithSHA()
+	case "fnv":
+		opt = bf.WithFNV()
+	default:
+		return nil, ErrUnknownHasher
+	}
+
+	opts := []bf.OptionFunc{opt, bf.WithConcurrency(), bf.WithMaxCapacity(maxCapacity)}
+	if req.DistinctCount {
+		opts = append(opts, bf.WithDistinctCount())
+	}
+
+	if req.Capacity > 0 || req.HashFunctions > 0 {
+		return bf.New(bf.WithCapacity(req.Capacity, req.HashFunctions), opts...)
+	}
+	return bf.New(bf.WithAccuracy(req.ErrorRate, req.Items), opts...)
+}
+
+func (s *Server) readSnapshot(r *http.Request) (bf.BloomFilter, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, badRequest{err}
+	}
+	return bf.Unmarshal(data, bf.WithConcurrency(), bf.WithMaxCapacity(s.maxCapacity))
+}
+
+type badRequest struct {
+	err error
+}
+
+func (b badRequest) Error() string {
+	return b.err.Error()
+}
+
+func (b badRequest) Unwrap() error {
+	return b.err
+}
+
+func statusOf(err error) int {
+	var br badRequest
+	var mbe *http.MaxBytesError
+	switch {
+	case errors.As(err, &mbe), errors.Is(err, bf.ErrStorageCapacityTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrFilterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrFilterExists),
+		errors.Is(err, bf.ErrStorageDifference),
+		errors.Is(err, bf.ErrHasherDifference):
+		return http.StatusConflict
+	case errors.As(err, &br),
+		errors.Is(err, ErrInvalidFilterName),
+		errors.Is(err, ErrUnknownHasher),
+		errors.Is(err, bf.ErrInvalidSerializedData),
+		errors.Is(err, bf.ErrUnsupportedSerializationVersion),
+		errors.Is(err, bf.ErrUnsupportedHasher):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, statusOf(err), ErrorResponse{Error: err.Error()})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: http.StatusText(http.StatusMethodNotAllowed)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/toniphan21/go-bf"
+)
+
+func request(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func assertStatus(t *testing.T, w *httptest.ResponseRecorder, expected int) {
+	if w.Code != expected {
+		t.Errorf("expected status %d, got %d: %s", expected, w.Code, w.Body.String())
+	}
+}
+
+func TestServer_CreateInfoListAndDelete(t *testing.T) {
+	s := New()
+
+	assertStatus(t, request(t, s, http.MethodPut, "/filters/a", `{"error_rate":0.01,"items":1000,"hasher":"fnv"}`), http.StatusCreated)
+	assertStatus(t, request(t, s, http.MethodPut, "/filters/b", `{"capacity":4096,"hash_functions":3}`), http.StatusCreated)
+	assertStatus(t, request(t, s, http.MethodPut, "/filters/a", `{}`), http.StatusConflict)
+	assertStatus(t, request(t, s, http.MethodPut, "/filters/c", `{"hasher":"md5"}`), http.StatusBadRequest)
+	assertStatus(t, request(t, s, http.MethodPut, "/filters/c", `not json`), http.StatusBadRequest)
+
+	w := request(t, s, http.MethodGet, "/filters", "")
+	assertStatus(t, w, http.StatusOK)
+	if strings.TrimSpace(w.Body.String()) != `["a","b"]` {
+		t.Errorf("unexpected list %v", w.Body.String())
+	}
+
+	w = request(t, s, http.MethodGet, "/filters/b", "")
+	assertStatus(t, w, http.StatusOK)
+	var info Info
+	_ = json.Unmarshal(w.Body.Bytes(), &info)
+	if info.Name != "b" || info.Capacity != 4096 || info.HashFunctions != 3 || info.Count != 0 {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	f, _ := s.Filter("a")
+	if f.Config().StorageCapacity() != bf.WithAccuracy(0.01, 1000).StorageCapacity() {
+		t.Errorf("unexpected filter %v", f.Config().Info())
+	}
+
+	assertStatus(t, request(t, s, http.MethodDelete, "/filters/a", ""), http.StatusNoContent)
+	assertStatus(t, request(t, s, http.MethodDelete, "/filters/a", ""), http.StatusNotFound)
+	assertStatus(t, request(t, s, http.MethodGet, "/filters/a", ""), http.StatusNotFound)
+}
+
+func TestServer_RejectsLargeBodiesAndFilters(t *testing.T) {
+	s := New(WithMaxBodySize(64), WithMaxCapacity(1<<16))
+	request(t, s, http.MethodPut, "/filters/a", `{"capacity":4096,"hash_functions":3}`)
+
+	large := strings.Repeat("x", 65)
+	assertStatus(t, request(t, s, http.MethodPost, "/filters/a/add", large), http.StatusRequestEntityTooLarge)
+	assertStatus(t, request(t, s, http.MethodPost, "/filters/a/add-batch", `{"items":["`+large+`"]}`), http.StatusRequestEntityTooLarge)
+	assertStatus(t, request(t, s, http.MethodPut, "/filters/b", `{"capacity":65537,"hash_functions":3}`), http.StatusRequestEntityTooLarge)
+	assertStatus(t, request(t, s, http.MethodPost, "/filters/a/add", strings.Repeat("x", 64)), http.StatusNoContent)
+
+	// an encoded filter could be much smaller than its capacity
+	data, _ := bf.Marshal(bf.Must(bf.WithCapacity(1<<20, 3)))
+	s = New(WithMaxCapacity(1 << 16))
+	assertStatus(t, request(t, s, http.MethodPut, "/filters/c/snapshot", string(data)), http.StatusRequestEntityTooLarge)
+	if _, ok := s.Filter("c"); ok {
+		t.Errorf("expected the filter is not created")
+	}
+}
+
+func TestServer_AddAndExists(t *testing.T) {
+	s := New()
+	request(t, s, http.MethodPut, "/filters/a", `{"capacity":4096,"hash_functions":3}`)
+
+	assertStatus(t, request(t, s, http.MethodPost, "/filters/a/add", "hello"), http.StatusNoContent)
+	assertStatus(t, request(t, s, http.MethodPost, "/filters/a/add-batch", `{"items":["d29ybGQ="]}`), http.StatusNoContent)
+
+	w := request(t, s, http.MethodPost, "/filters/a/exists", "hello")
+	if strings.TrimSpace(w.Body.String()) != `{"exists":true}` {
+		t.Errorf("unexpected response %v", w.Body.String())
+	}
+
+	w = request(t, s, http.MethodPost, "/filters/a/exists-batch", `{"items":["aGVsbG8=","d29ybGQ=","b3RoZXI="]}`)
+	if strings.TrimSpace(w.Body.String()) != `{"results":[true,true,false]}` {
+		t.Errorf("unexpected response %v", w.Body.String())
+	}
+
+	assertStatus(t, request(t, s, http.MethodPost, "/filters/x/add", "hello"), http.StatusNotFound)
+	assertStatus(t, request(t, s, http.MethodGet, "/filters/a/add", "hello"), http.StatusMethodNotAllowed)
+	assertStatus(t, request(t, s, http.MethodPost, "/filters/a/add-batch", "{"), http.StatusBadRequest)
+}
+
+func TestServer_SnapshotUnionAndIntersect(t *testing.T) {
+	s := New()
+	request(t, s, http.MethodPut, "/filters/a", `{"capacity":4096,"hash_functions":3}`)
+	request(t, s, http.MethodPost, "/filters/a/add", "a")
+
+	other := bf.Must(bf.WithCapacity(4096, 3))
+	other.Add([]byte("b"))
+	data, _ := bf.Marshal(other)
+
+	assertStatus(t, request(t, s, http.MethodPost, "/filters/a/union", string(data)), http.StatusNoContent)
+	f, _ := s.Filter("a")
+	if !f.Exists([]byte("a")) || !f.Exists([]byte("b")) {
+		t.Errorf("expected a and b exist after union")
+	}
+
+	assertStatus(t, request(t, s, http.MethodPost, "/filters/a/intersect", string(data)), http.StatusNoContent)
+	if f.Exists([]byte("a")) || !f.Exists([]byte("b")) {
+		t.Errorf("expected only b exists after intersect")
+	}
+
+	incompatible, _ := bf.Marshal(bf.Must(bf.WithCapacity(2048, 3)))
+	assertStatus(t, request(t, s, http.MethodPost, "/filters/a/union", string(incompatible)), http.StatusConflict)
+	assertStatus(t, request(t, s, http.MethodPost, "/filters/a/union", "garbage"), http.StatusBadRequest)
+
+	w := request(t, s, http.MethodGet, "/filters/a/snapshot", "")
+	assertStatus(t, w, http.StatusOK)
+	downloaded, err := bf.Unmarshal(w.Body.Bytes())
+	if err != nil || !downloaded.Exists([]byte("b")) {
+		t.Errorf("expected snapshot contains b, got %v", err)
+	}
+
+	assertStatus(t, request(t, s, http.MethodPut, "/filters/c/snapshot", string(data)), http.StatusNoContent)
+	c, ok := s.Filter("c")
+	if !ok || !c.Exists([]byte("b")) {
+		t.Errorf("expected uploaded snapshot contains b")
+	}
+	assertStatus(t, request(t, s, http.MethodPut, "/filters/c/snapshot", "garbage"), http.StatusBadRequest)
+}
+
+func TestServer_NotFoundRoutes(t *testing.T) {
+	s := New()
+	for _, path := range []string{"/", "/other", "/filters/a/b/c", "/filters/a/unknown"} {
+		assertStatus(t, request(t, s, http.MethodGet, path, ""), http.StatusNotFound)
+	}
+	assertStatus(t, request(t, s, http.MethodGet, "/filters/", ""), http.StatusOK)
+	assertStatus(t, request(t, s, http.MethodPost, "/filters", ""), http.StatusMethodNotAllowed)
+}
+
+func TestServer_ConcurrentRequests(t *testing.T) {
+	ts := httptest.NewServer(New())
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, ts.URL+"/filters/a", strings.NewReader(`{"capacity":65536,"hash_functions":3}`))
+	resp, _ := http.DefaultClient.Do(req)
+	resp.Body.Close()
+
+	done := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		go func(i int) {
+			defer func() { done <- struct{}{} }()
+			for j := 0; j < 20; j++ {
+				resp, err := http.Post(ts.URL+"/filters/a/add", "", bytes.NewReader([]byte{byte(i), byte(j)}))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		}(i)
+	}
+	for i := 0; i < 8; i++ {
+		<-done
+	}
+
+	resp, _ = http.Get(ts.URL + "/filters/a")
+	var info Info
+	_ = json.NewDecoder(resp.Body).Decode(&info)
+	resp.Body.Close()
+	if info.Count != 160 {
+		t.Errorf("expected 160, got %v", info.Count)
+	}
+}
@@ -0,0 +1,165 @@
+package bf
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+	"path/filepath"
+)
+
+var shardTable = crc32.MakeTable(crc32.Castagnoli)
+
+/*
+ShardedBloomFilter routes every item by a CRC-32C hash, which is independent of
+the built-in hashers, to one of N sub-filters with their own Storage so a big
+filter does not need a single huge allocation. Sub-filters created
+WithConcurrency are locked independently, the ShardedBloomFilter itself has no
+lock and is safe for concurrent use if its sub-filters are.
+*/
+type ShardedBloomFilter struct {
+	shards []BloomFilter
+}
+
+/*
+NewSharded creates a ShardedBloomFilter of n sub-filters, every sub-filter is
+created by New with the given Config and options. The Config is used for each
+shard, for example 64 shards of WithAccuracy(0.001, 1_000_000) hold about 64
+million items.
+*/
+func NewSharded(n int, config Config, opts ...OptionFunc) (*ShardedBloomFilter, error) {
+	return newSharded(n, func(int) (BloomFilter, error) {
+		return New(config, opts...)
+	})
+}
+
+/*
+OpenDurableSharded opens or creates a ShardedBloomFilter of n DurableBloomFilter
+in sub directories shard-0000, shard-0001... of dir, so every shard has its own
+snapshot and write-ahead log. The number of shards must not be changed after
+items are added.
+*/
+func OpenDurableSharded(dir string, n int, config Config, opts ...DurableOptionFunc) (*ShardedBloomFilter, error) {
+	return newSharded(n, func(i int) (BloomFilter, error) {
+		return OpenDurable(filepath.Join(dir, fmt.Sprintf("shard-%04d", i)), config, opts...)
+	})
+}
+
+func newSharded(n int, open func(i int) (BloomFilter, error)) (*ShardedBloomFilter, error) {
+	if n <= 0 {
+		return nil, ErrInvalidNumberOfShards
+	}
+
+	s := &ShardedBloomFilter{shards: make([]BloomFilter, 0, n)}
+	for i := 0; i < n; i++ {
+		f, err := open(i)
+		if err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+		s.shards = append(s.shards, f)
+	}
+	return s, nil
+}
+
+func (s *ShardedBloomFilter) Add(item []byte) {
+	s.shards[s.ShardOf(item)].Add(item)
+}
+
+func (s *ShardedBloomFilter) Exists(item []byte) bool {
+	return s.shards[s.ShardOf(item)].Exists(item)
+}
+
+func (s *ShardedBloomFilter) TestAndAdd(item []byte) bool {
+	return s.shards[s.ShardOf(item)].TestAndAdd(item)
+}
+
+// Count returns the sum of Count of all shards, -1 if any of them is unknown.
+func (s *ShardedBloomFilter) Count() int {
+	return s.sum(BloomFilter.Count)
+}
+
+func (s *ShardedBloomFilter) AddCount() int {
+	return s.sum(BloomFilter.AddCount)
+}
+
+func (s *ShardedBloomFilter) DistinctCount() int {
+	return s.sum(BloomFilter.DistinctCount)
+}
+
+// ShardOf returns index of the shard which the item belongs to.
+func (s *ShardedBloomFilter) ShardOf(item []byte) int {
+	h := crc32.Checksum(item, shardTable)
+	return int(uint64(h) * uint64(len(s.shards)) >> 32)
+}
+
+// Shards returns the sub-filters, they could be persisted or inspected one by
+// one.
+func (s *ShardedBloomFilter) Shards() []BloomFilter {
+	return s.shards
+}
+
+// Union applies Union shard by shard, nothing is changed if any pair of shards
+// is not Compatible.
+func (s *ShardedBloomFilter) Union(other *ShardedBloomFilter) error {
+	return s.each(other, BloomFilter.Union)
+}
+
+// Intersect applies Intersect shard by shard, nothing is changed if any pair
+// of shards is not Compatible.
+func (s *ShardedBloomFilter) Intersect(other *ShardedBloomFilter) error {
+	return s.each(other, BloomFilter.Intersect)
+}
+
+// Clone returns a copy of all shards, shards of a durable filter are copied
+// in memory.
+func (s *ShardedBloomFilter) Clone() (*ShardedBloomFilter, error) {
+	return newSharded(len(s.shards), func(i int) (BloomFilter, error) {
+		return s.shards[i].Clone()
+	})
+}
+
+// Close closes every shard which is an io.Closer and returns the first error.
+func (s *ShardedBloomFilter) Close() error {
+	var err error
+	for _, f := range s.shards {
+		if c, ok := f.(io.Closer); ok {
+			if cErr := c.Close(); err == nil {
+				err = cErr
+			}
+		}
+	}
+	return err
+}
+
+func (s *ShardedBloomFilter) each(other *ShardedBloomFilter, fn func(BloomFilter, ReadOnlyFilter) error) error {
+	if other == nil {
+		return ErrNilBloomFilter
+	}
+	if len(other.shards) != len(s.shards) {
+		return ErrShardsDifference
+	}
+	for i := range s.shards {
+		if err := Compatible(s.shards[i], other.shards[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range s.shards {
+		if err := fn(s.shards[i], other.shards[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ShardedBloomFilter) sum(fn func(BloomFilter) int) int {
+	var result int
+	for _, f := range s.shards {
+		n := fn(f)
+		if n < 0 {
+			return -1
+		}
+		result += n
+	}
+	return result
+}
@@ -0,0 +1,152 @@
+package bf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestNewSharded_ReturnsErrIfArgumentsAreInvalid(t *testing.T) {
+	if _, err := NewSharded(0, WithCapacity(1024, 3)); !errors.Is(err, ErrInvalidNumberOfShards) {
+		t.Errorf("expected %v, got %v", ErrInvalidNumberOfShards, err)
+	}
+	if _, err := NewSharded(4, nil); !errors.Is(err, ErrNilConfig) {
+		t.Errorf("expected %v, got %v", ErrNilConfig, err)
+	}
+}
+
+func TestShardedBloomFilter_AddAndExists(t *testing.T) {
+	s, _ := NewSharded(8, WithAccuracy(0.01, 1000), WithConcurrency())
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				s.Add([]byte(fmt.Sprintf("%d-%d", g, i)))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for g := 0; g < 8; g++ {
+		for i := 0; i < 500; i++ {
+			if !s.Exists([]byte(fmt.Sprintf("%d-%d", g, i))) {
+				t.Fatalf("Bloom Filter has false negative")
+			}
+		}
+	}
+	if s.Count() != 4000 || s.AddCount() != 4000 || s.DistinctCount() != -1 {
+		t.Errorf("expected counters 4000, 4000, -1, got %v, %v, %v", s.Count(), s.AddCount(), s.DistinctCount())
+	}
+
+	// items are spread over all shards
+	for i, f := range s.Shards() {
+		if f.Count() < 350 || f.Count() > 650 {
+			t.Errorf("expected about 500 items in shard %v, got %v", i, f.Count())
+		}
+	}
+	if s.TestAndAdd([]byte("new")) || !s.TestAndAdd([]byte("new")) {
+		t.Errorf("unexpected result of TestAndAdd")
+	}
+}
+
+func TestShardedBloomFilter_ShardOf(t *testing.T) {
+	a, _ := NewSharded(64, WithCapacity(64, 1))
+	b, _ := NewSharded(64, WithCapacity(128, 2))
+	for i := 0; i < 100; i++ {
+		item := []byte(fmt.Sprintf("%d", i))
+		if a.ShardOf(item) != b.ShardOf(item) || a.ShardOf(item) >= 64 {
+			t.Errorf("expected routing only depends on the item and number of shards")
+		}
+	}
+}
+
+func TestShardedBloomFilter_UnionIntersectAndClone(t *testing.T) {
+	a, _ := NewSharded(4, WithCapacity(4096, 3))
+	b, _ := NewSharded(4, WithCapacity(4096, 3))
+	a.Add([]byte("a"))
+	a.Add([]byte("shared"))
+	b.Add([]byte("b"))
+	b.Add([]byte("shared"))
+
+	u, _ := a.Clone()
+	if err := u.Union(b); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if !u.Exists([]byte("a")) || !u.Exists([]byte("b")) || !u.Exists([]byte("shared")) {
+		t.Errorf("unexpected union")
+	}
+	if a.Exists([]byte("b")) {
+		t.Errorf("expected Clone is independent")
+	}
+
+	if err := a.Intersect(b); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if a.Exists([]byte("a")) || !a.Exists([]byte("shared")) {
+		t.Errorf("unexpected intersection")
+	}
+	if a.Count() != -1 {
+		t.Errorf("expected count is unknown, got %v", a.Count())
+	}
+}
+
+func TestShardedBloomFilter_ReturnsErrIfShardsAreDifferent(t *testing.T) {
+	a, _ := NewSharded(4, WithCapacity(4096, 3))
+	a.Add([]byte("a"))
+
+	if err := a.Union(nil); !errors.Is(err, ErrNilBloomFilter) {
+		t.Errorf("expected %v, got %v", ErrNilBloomFilter, err)
+	}
+	b, _ := NewSharded(2, WithCapacity(4096, 3))
+	if err := a.Union(b); !errors.Is(err, ErrShardsDifference) {
+		t.Errorf("expected %v, got %v", ErrShardsDifference, err)
+	}
+
+	// the last shard differs, no shard is changed
+	c, _ := NewSharded(4, WithCapacity(4096, 3))
+	c.shards[3] = Must(WithCapacity(2048, 3))
+	var ie *IncompatibleError
+	if err := a.Intersect(c); !errors.As(err, &ie) {
+		t.Errorf("expected IncompatibleError, got %v", err)
+	}
+	if !a.Exists([]byte("a")) {
+		t.Errorf("expected no shard is changed")
+	}
+}
+
+func TestOpenDurableSharded(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenDurableSharded(dir, 4, WithCapacity(4096, 3), WithSyncNever())
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		s.Add([]byte(fmt.Sprintf("item-%d", i)))
+	}
+	if err = s.Close(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("shard-%04d", i), durableLogFile)); err != nil {
+			t.Errorf("expected log of shard %v, got %v", i, err)
+		}
+	}
+
+	r, _ := OpenDurableSharded(dir, 4, WithCapacity(4096, 3))
+	defer r.Close()
+	for i := 0; i < 100; i++ {
+		if !r.Exists([]byte(fmt.Sprintf("item-%d", i))) {
+			t.Errorf("expected item-%d exists", i)
+		}
+	}
+	if r.Count() != 100 {
+		t.Errorf("expected 100, got %v", r.Count())
+	}
+}
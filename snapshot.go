@@ -0,0 +1,131 @@
+package bf
+
+type readOnlyFilter struct {
+	filter *bloomFilter
+}
+
+/*
+Snapshot returns a point-in-time ReadOnlyFilter of the given filter, later
+changes of the filter are not seen by the snapshot. The built-in filters take
+a snapshot in O(1): pages of the built-in Storage are shared and copied by the
+filter before they are changed, so the snapshot could be queried or marshaled
+without blocking writers. Filters of other implementations are cloned.
+*/
+func Snapshot(f BloomFilter) (ReadOnlyFilter, error) {
+	if f == nil {
+		return nil, ErrNilBloomFilter
+	}
+
+	if s, ok := f.(interface{ Snapshot() ReadOnlyFilter }); ok {
+		return s.Snapshot(), nil
+	}
+	return f.Clone()
+}
+
+/*
+Freeze returns an immutable ReadOnlyFilter of the current data of the given
+filter, the given filter is not changed and could still be used. The built-in
+filters are frozen in O(1) by a Snapshot: its Storage rejects Set, Clear and
+batch operations by panicking with ErrReadOnlyStorage. Filters of other
+implementations are cloned then frozen, unlike Snapshot which returns the clone
+as it is.
+*/
+func Freeze(f BloomFilter) (ReadOnlyFilter, error) {
+	if f == nil {
+		return nil, ErrNilBloomFilter
+	}
+
+	if s, ok := f.(interface{ Snapshot() ReadOnlyFilter }); ok {
+		return s.Snapshot(), nil
+	}
+
+	r, err := f.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := bloomFilterOf(r); ok {
+		return b.Snapshot(), nil
+	}
+	return nil, ErrUnsupportedBloomFilter
+}
+
+// Snapshot shares pages of the built-in Storage, bits of other Storage are
+// copied into the built-in Storage.
+func (b *bloomFilter) Snapshot() ReadOnlyFilter {
+	r := *b
+	r.storage = snapshotStorage(b.storage)
+	return &readOnlyFilter{filter: &r}
+}
+
+// Snapshot holds the write lock only while pages are shared.
+func (c *concurrentBloomFilter) Snapshot() ReadOnlyFilter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.filter.Snapshot()
+}
+
+// Snapshot returns an in-memory point-in-time view of the filter.
+func (d *DurableBloomFilter) Snapshot() ReadOnlyFilter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.filter.Snapshot()
+}
+
+// snapshotStorage copies bits of a Storage which could not share its words, a
+// bitset made by MmapStorageFactory is released with its own memory.
+func snapshotStorage(s Storage) Storage {
+	if b, ok := s.(*bitset); ok && b.release == nil {
+		return b.snapshot()
+	}
+
+	b := newBitset((s.Capacity()+bitsetDataSize-1)/bitsetDataSize, s.Capacity())
+	if o, ok := s.(*bitset); ok {
+		b.Copy(o)
+	} else {
+		for i := uint32(0); i < s.Capacity(); i++ {
+			if s.Get(i) {
+				b.Set(i)
+			}
+		}
+	}
+	b.readOnly = true
+	return b
+}
+
+func (r *readOnlyFilter) Exists(item []byte) bool {
+	return r.filter.Exists(item)
+}
+
+func (r *readOnlyFilter) Keys(item []byte) KeyHandle {
+	return r.filter.Keys(item)
+}
+
+func (r *readOnlyFilter) ExistsKeys(h KeyHandle) (bool, error) {
+	return r.filter.ExistsKeys(h)
+}
+
+func (r *readOnlyFilter) Count() int {
+	return r.filter.Count()
+}
+
+func (r *readOnlyFilter) AddCount() int {
+	return r.filter.AddCount()
+}
+
+func (r *readOnlyFilter) DistinctCount() int {
+	return r.filter.DistinctCount()
+}
+
+func (r *readOnlyFilter) Storage() Storage {
+	return r.filter.Storage()
+}
+
+func (r *readOnlyFilter) Hasher() Hasher {
+	return r.filter.Hasher()
+}
+
+func (r *readOnlyFilter) Config() Config {
+	return r.filter.Config()
+}
@@ -0,0 +1,318 @@
+package bf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func bitsOfStorageForTest(s Storage) []bool {
+	result := make([]bool, s.Capacity())
+	for i := range result {
+		result[i] = s.Get(uint32(i))
+	}
+	return result
+}
+
+func assertStorageBits(t *testing.T, s Storage, expected []bool) {
+	if s.Capacity() != uint32(len(expected)) {
+		t.Fatalf("expected capacity %v, got %v", len(expected), s.Capacity())
+	}
+	for i, bit := range expected {
+		if s.Get(uint32(i)) != bit {
+			t.Fatalf("unexpected bit %v", i)
+		}
+	}
+}
+
+func assertReadOnlyStoragePanics(t *testing.T, fn func()) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != ErrReadOnlyStorage {
+			t.Errorf("expected panic with %v, got %v", ErrReadOnlyStorage, r)
+		}
+	}()
+	fn()
+}
+
+func TestBitset_Snapshot(t *testing.T) {
+	// 3 pages, the last one is not full
+	capacity := uint32(2*bitsetPageSize*bitsetDataSize + 100)
+	s, _ := memoryStorageFactory{}.Make(capacity)
+	b := s.(*bitset)
+	for i := uint32(0); i < capacity; i += 7 {
+		b.Set(i)
+	}
+	expected := bitsOfStorageForTest(b)
+
+	first := b.snapshot()
+	if b.cow == nil || b.cow.pages != nil {
+		t.Fatalf("expected words are shared without splitting pages")
+	}
+	second := b.snapshot()
+
+	b.Set(1)
+	b.Clear(capacity - 1)
+	if len(b.cow.pages) != 3 || len(b.cow.pages[2]) != 2 || b.cow.owned[0] != 0b101 {
+		t.Fatalf("expected the first and last page are copied, got %v", b.cow.owned)
+	}
+	afterFirst := bitsOfStorageForTest(b)
+
+	third := b.snapshot()
+	b.Set(2)
+	b.Set(bitsetPageSize*bitsetDataSize + 1)
+	b.Clear(0)
+
+	assertStorageBits(t, first, expected)
+	assertStorageBits(t, second, expected)
+	assertStorageBits(t, third, afterFirst)
+	if !b.Get(1) || !b.Get(2) || b.Get(0) || b.Get(capacity-1) || !b.Get(bitsetPageSize*bitsetDataSize+1) {
+		t.Errorf("expected changes are seen by the bitset")
+	}
+	if b.size() != len(first.data) || third.size() != len(first.data) {
+		t.Errorf("expected size %v, got %v, %v", len(first.data), b.size(), third.size())
+	}
+
+	// snapshots are read-only
+	assertReadOnlyStoragePanics(t, func() { first.Set(1) })
+	assertReadOnlyStoragePanics(t, func() { third.Clear(1) })
+	assertReadOnlyStoragePanics(t, func() { third.Union(b) })
+	assertReadOnlyStoragePanics(t, func() { third.Reset() })
+	if err := ImportStorage(third, third.exportBytes()); !errors.Is(err, ErrReadOnlyStorage) {
+		t.Errorf("expected %v, got %v", ErrReadOnlyStorage, err)
+	}
+	if first.Get(1) || !third.Get(1) {
+		t.Errorf("expected snapshots are not changed")
+	}
+	if r := third.snapshot(); r.Get(2) || !r.Get(1) {
+		t.Errorf("expected snapshot of a snapshot is the same")
+	}
+}
+
+func TestBitset_SnapshotIsNotChangedByBatchOperations(t *testing.T) {
+	capacity := uint32(3 * bitsetPageSize * bitsetDataSize)
+	other := newBitset(capacity/bitsetDataSize, capacity)
+	for i := uint32(0); i < capacity; i += 3 {
+		other.Set(i)
+	}
+
+	cases := []struct {
+		name string
+		op   func(b *bitset)
+	}{
+		{name: "union", op: func(b *bitset) { b.Union(other) }},
+		{name: "intersect", op: func(b *bitset) { b.Intersect(other) }},
+		{name: "difference", op: func(b *bitset) { b.Difference(other) }},
+		{name: "xor", op: func(b *bitset) { b.Xor(other) }},
+		{name: "reset", op: func(b *bitset) { b.Reset() }},
+		{name: "copy", op: func(b *bitset) { b.Copy(other) }},
+		{name: "import", op: func(b *bitset) { b.importBytes(other.exportBytes()) }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, split := range []bool{false, true} {
+				b := newBitset(capacity/bitsetDataSize, capacity)
+				for i := uint32(0); i < capacity; i += 5 {
+					b.Set(i)
+				}
+				snap := b.snapshot()
+				if split {
+					b.Set(1)
+					snap = b.snapshot()
+				}
+				before := bitsOfStorageForTest(snap)
+
+				expected := newBitset(capacity/bitsetDataSize, capacity)
+				expected.Copy(b)
+				tc.op(expected)
+				tc.op(b)
+
+				if b.cow != nil && tc.name != "copy" && tc.name != "reset" {
+					t.Errorf("expected the bitset owns its words")
+				}
+				assertStorageBits(t, snap, before)
+				assertStorageBits(t, b, bitsOfStorageForTest(expected))
+				if b.count() != expected.count() || string(b.exportBytes()) != string(expected.exportBytes()) {
+					t.Errorf("expected the same words")
+				}
+			}
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	cases := []struct {
+		name string
+		f    BloomFilter
+	}{
+		{name: "bitset", f: Must(WithCapacity(1<<16, 3))},
+		{name: "distinct count", f: Must(WithCapacity(1<<16, 3), WithDistinctCount())},
+		{name: "concurrent", f: Must(WithCapacity(1<<16, 3), WithConcurrency())},
+		{name: "mmap", f: Must(WithCapacity(1<<16, 3), WithStorage(MmapStorageFactory{}))},
+		{name: "custom storage", f: Must(WithCapacity(1<<16, 3), WithStorage(sliceStorageFactory{}))},
+		{name: "durable", f: openDurableForTest(t, t.TempDir())},
+		{name: "other implementation", f: wrappedBloomFilter{Must(WithCapacity(1<<16, 3))}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := tc.f
+			for i := 0; i < 100; i++ {
+				f.Add([]byte(fmt.Sprintf("item-%d", i)))
+			}
+			before, _ := Marshal(f, WithMarshalIndexMapping(IndexMappingMultiplyShift))
+
+			snap, err := Snapshot(f)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			for i := 100; i < 200; i++ {
+				f.Add([]byte(fmt.Sprintf("item-%d", i)))
+			}
+			f.Add([]byte("other"))
+
+			for i := 0; i < 100; i++ {
+				if !snap.Exists([]byte(fmt.Sprintf("item-%d", i))) {
+					t.Fatalf("expected item-%d exists", i)
+				}
+			}
+			if snap.Exists([]byte("other")) || !f.Exists([]byte("other")) {
+				t.Errorf("expected later changes are not seen by the snapshot")
+			}
+			if snap.Count() != 100 || snap.AddCount() != 100 {
+				t.Errorf("expected count 100, got %v, %v", snap.Count(), snap.AddCount())
+			}
+			if snap.Config() != f.Config() || !snap.Hasher().Equals(f.Hasher()) {
+				t.Errorf("expected the same config and hasher")
+			}
+			if ok, err := snap.ExistsKeys(f.Keys([]byte("item-1"))); !ok || err != nil {
+				t.Errorf("expected true, got %v, %v", ok, err)
+			}
+
+			after, err := Marshal(snap)
+			if err != nil || string(before) != string(after) {
+				t.Errorf("expected the snapshot is marshaled as the filter was, got %v", err)
+			}
+		})
+	}
+
+	if _, err := Snapshot(nil); !errors.Is(err, ErrNilBloomFilter) {
+		t.Errorf("expected %v, got %v", ErrNilBloomFilter, err)
+	}
+}
+
+func TestSnapshot_ReadersAndWriterRunConcurrently(t *testing.T) {
+	f := Must(WithCapacity(1<<20, 3), WithConcurrency())
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			f.Add([]byte(fmt.Sprintf("item-%d", i)))
+			if i%100 == 0 {
+				_ = f.Union(Must(WithCapacity(1<<20, 3)))
+			}
+		}
+	}()
+
+	for n := 0; n < 20; n++ {
+		snap, _ := Snapshot(f)
+		count := snap.AddCount()
+		first, _ := Marshal(snap)
+
+		var readers sync.WaitGroup
+		for r := 0; r < 4; r++ {
+			readers.Add(1)
+			go func() {
+				defer readers.Done()
+				data, _ := Marshal(snap)
+				if string(data) != string(first) || snap.AddCount() != count {
+					t.Errorf("expected the snapshot is not changed")
+				}
+			}()
+		}
+		readers.Wait()
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestFreeze(t *testing.T) {
+	cases := []struct {
+		name string
+		f    BloomFilter
+	}{
+		{name: "bitset", f: Must(WithCapacity(1<<16, 3))},
+		{name: "concurrent", f: Must(WithCapacity(1<<16, 3), WithConcurrency())},
+		{name: "mmap", f: Must(WithCapacity(1<<16, 3), WithStorage(MmapStorageFactory{}))},
+		{name: "custom storage", f: Must(WithCapacity(1<<16, 3), WithStorage(sliceStorageFactory{}))},
+		{name: "durable", f: openDurableForTest(t, t.TempDir())},
+		{name: "other implementation", f: wrappedBloomFilter{Must(WithCapacity(1<<16, 3))}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := tc.f
+			for i := 0; i < 100; i++ {
+				f.Add([]byte(fmt.Sprintf("item-%d", i)))
+			}
+			before, _ := Marshal(f, WithMarshalIndexMapping(IndexMappingMultiplyShift))
+
+			r, err := Freeze(f)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if _, ok := r.(BloomFilter); ok {
+				t.Errorf("expected the frozen filter could not be changed")
+			}
+			if r.Storage() == f.Storage() {
+				t.Errorf("expected the storage of the filter is not frozen")
+			}
+			if after, err := Marshal(r); err != nil || string(before) != string(after) {
+				t.Errorf("expected the frozen filter is marshaled as the filter was, got %v", err)
+			}
+			if !r.Exists([]byte("item-1")) || r.Count() != 100 {
+				t.Errorf("expected the same data")
+			}
+			assertReadOnlyStoragePanics(t, func() { r.Storage().Set(0) })
+			assertReadOnlyStoragePanics(t, func() { r.Storage().Clear(0) })
+
+			// the filter keeps working and the frozen filter is not changed
+			f.Add([]byte("other"))
+			f.Storage().Set(0)
+			c, _ := f.Clone()
+			if err = f.Union(c); err != nil {
+				t.Errorf("expected nil, got %v", err)
+			}
+			if !f.Exists([]byte("other")) {
+				t.Errorf("expected the filter is changed")
+			}
+			if r.Exists([]byte("other")) || r.Count() != 100 {
+				t.Errorf("expected the frozen filter is not changed")
+			}
+			f.Reset()
+			if !r.Exists([]byte("item-1")) {
+				t.Errorf("expected the frozen filter is not reset")
+			}
+
+			// a frozen filter could be merged into a mutable one
+			target := Must(r.Config(), WithStorage(MmapStorageFactory{}))
+			if err = target.CopyFrom(r); err != nil || !target.Exists([]byte("item-1")) || target.Count() != 100 {
+				t.Errorf("expected the frozen filter is copied, got %v", err)
+			}
+		})
+	}
+
+	if _, err := Freeze(nil); !errors.Is(err, ErrNilBloomFilter) {
+		t.Errorf("expected %v, got %v", ErrNilBloomFilter, err)
+	}
+}
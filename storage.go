@@ -20,6 +20,30 @@ type BatchUnion interface {
 	Union(other Storage)
 }
 
+type BatchDifference interface {
+	Difference(other Storage)
+}
+
+type BatchXor interface {
+	Xor(other Storage)
+}
+
+// BatchReset is implemented by a Storage which could clear every bit at once.
+type BatchReset interface {
+	Reset()
+}
+
+// BatchCopy is implemented by a Storage which could copy every bit of another
+// Storage of the same kind at once.
+type BatchCopy interface {
+	Copy(other Storage)
+}
+
+// Releaser is implemented by a Storage which holds memory outside the Go heap.
+type Releaser interface {
+	Release() error
+}
+
 type StorageFactory interface {
 	Make(capacity uint32) (Storage, error)
 }
@@ -0,0 +1,141 @@
+package bf
+
+// bitsetPageSize is the number of words of a page which is copied on write
+// after a snapshot is taken, 4 KiB.
+const bitsetPageSize = 512
+
+/*
+cowPages holds words of a bitset which are shared with read-only snapshots.
+Right after the first snapshot every word is in base, the table of pages is
+split from base by the first change. A page is copied before it is changed
+unless it is owned, every page is shared again by the next snapshot. Words
+which are shared are never changed, so snapshots are read without locks.
+*/
+type cowPages struct {
+	base        []uint64
+	pages       [][]uint64
+	owned       []uint64
+	sharedTable bool
+}
+
+// snapshot returns a read-only bitset of the current words in O(1), b could be
+// changed afterwards without changing the snapshot.
+func (b *bitset) snapshot() *bitset {
+	r := &bitset{capacity: b.capacity, readOnly: true}
+	switch {
+	case b.readOnly:
+		r.data, r.cow = b.data, b.cow
+	case b.cow == nil:
+		r.data = b.data
+		b.cow = &cowPages{base: b.data}
+		b.data = nil
+	case b.cow.pages == nil:
+		r.data = b.cow.base
+	default:
+		r.cow = &cowPages{pages: b.cow.pages, sharedTable: true}
+		b.cow.owned = nil
+		b.cow.sharedTable = true
+	}
+	return r
+}
+
+func (b *bitset) word(n uint32) uint64 {
+	if b.cow == nil {
+		return b.data[n]
+	}
+	if b.cow.pages == nil {
+		return b.cow.base[n]
+	}
+	return b.cow.pages[n/bitsetPageSize][n%bitsetPageSize]
+}
+
+// writable returns the word n after its page is copied if it is shared.
+func (c *cowPages) writable(n uint32) *uint64 {
+	if c.pages == nil {
+		for lo := 0; lo < len(c.base); lo += bitsetPageSize {
+			hi := lo + bitsetPageSize
+			if hi > len(c.base) {
+				hi = len(c.base)
+			}
+			c.pages = append(c.pages, c.base[lo:hi:hi])
+		}
+		c.base = nil
+	} else if c.sharedTable {
+		c.pages = append([][]uint64(nil), c.pages...)
+		c.sharedTable = false
+	}
+	if c.owned == nil {
+		c.owned = make([]uint64, (len(c.pages)+63)/64)
+	}
+
+	p := n / bitsetPageSize
+	if c.owned[p/64]&(1<<(p%64)) == 0 {
+		page := make([]uint64, len(c.pages[p]))
+		copy(page, c.pages[p])
+		c.pages[p] = page
+		c.owned[p/64] |= 1 << (p % 64)
+	}
+	return &c.pages[p][n%bitsetPageSize]
+}
+
+// eachPage calls fn with consecutive words of b and the offset of the first
+// one, words must not be changed.
+func (b *bitset) eachPage(fn func(offset int, words []uint64)) {
+	switch {
+	case b.cow == nil:
+		fn(0, b.data)
+	case b.cow.pages == nil:
+		fn(0, b.cow.base)
+	default:
+		for p, page := range b.cow.pages {
+			fn(p*bitsetPageSize, page)
+		}
+	}
+}
+
+// words returns every word of b, they are copied if pages are split. The
+// result must not be changed.
+func (b *bitset) words() []uint64 {
+	if b.cow == nil {
+		return b.data
+	}
+	if b.cow.pages == nil {
+		return b.cow.base
+	}
+
+	result := make([]uint64, 0, b.size())
+	for _, page := range b.cow.pages {
+		result = append(result, page...)
+	}
+	return result
+}
+
+func (b *bitset) size() int {
+	switch {
+	case b.cow == nil:
+		return len(b.data)
+	case b.cow.pages == nil:
+		return len(b.cow.base)
+	}
+
+	last := len(b.cow.pages) - 1
+	if last < 0 {
+		return 0
+	}
+	return last*bitsetPageSize + len(b.cow.pages[last])
+}
+
+// flatten makes b own contiguous words so they could be changed in place, the
+// words of snapshots are not changed.
+func (b *bitset) flatten() {
+	if b.cow == nil {
+		return
+	}
+
+	words := b.words()
+	if b.cow.pages == nil {
+		words = append([]uint64(nil), words...)
+	}
+	b.data = words
+	b.cow = nil
+}
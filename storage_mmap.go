@@ -0,0 +1,55 @@
+package bf
+
+/*
+MmapStorageFactory makes bitset storages in memory mapped anonymously from the
+operating system instead of the Go heap, so the memory is not scanned or zeroed
+by the runtime, pages are zeroed lazily by the operating system when they are
+first touched and a huge filter is created instantly. HugePages hints the
+operating system to back the memory by huge pages where it is supported.
+
+The memory is returned to the operating system only by Release of the Storage,
+a Storage which is dropped without Release keeps its memory until the process
+exits. Slices of the memory are borrowed by bulk operations, so a finalizer
+could unmap it while they are in use. Platforms without mmap use the Go heap.
+*/
+type MmapStorageFactory struct {
+	HugePages bool
+}
+
+func (f MmapStorageFactory) Make(capacity uint32) (Storage, error) {
+	if capacity <= 0 {
+		return nil, ErrInvalidStorageCapacity
+	}
+
+	n, m := capacity/bitsetDataSize, capacity%bitsetDataSize
+	if m > 0 {
+		n += 1
+	}
+
+	data, release, err := mmapWords(int(n), f.HugePages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bitset{data: data, capacity: capacity, release: release}, nil
+}
+
+// Release returns memory of a bitset made by MmapStorageFactory to the
+// operating system, the bitset has no capacity afterwards and every operation
+// on it returns early. It must not be called while the filter is in use.
+func (b *bitset) Release() error {
+	if b.release == nil {
+		return nil
+	}
+
+	err := b.release()
+	b.release = nil
+	b.data = nil
+	b.capacity = 0
+	return err
+}
+
+// released reports whether memory of b was returned by Release.
+func (b *bitset) released() bool {
+	return b.data == nil && b.cow == nil
+}
@@ -0,0 +1,8 @@
+package bf
+
+import "syscall"
+
+func adviseHugePages(mem []byte) {
+	// it is only a hint, the memory is still usable if it is not supported
+	_ = syscall.Madvise(mem, syscall.MADV_HUGEPAGE)
+}
@@ -0,0 +1,7 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package bf
+
+func mmapWords(n int, _ bool) ([]uint64, func() error, error) {
+	return make([]uint64, n), nil, nil
+}
@@ -0,0 +1,24 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package bf
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func mmapWords(n int, hugePages bool) ([]uint64, func() error, error) {
+	size := n * bitsetDataSize / 8
+	mem, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, nil, err
+	}
+	if hugePages {
+		adviseHugePages(mem)
+	}
+
+	data := unsafe.Slice((*uint64)(unsafe.Pointer(&mem[0])), n)
+	return data, func() error {
+		return syscall.Munmap(mem)
+	}, nil
+}
@@ -58,3 +58,130 @@ func TestMemoryStorageFactory_Make(t *testing.T) {
 		})
 	}
 }
+
+func TestMmapStorageFactory_Make(t *testing.T) {
+	if _, err := (MmapStorageFactory{}).Make(0); !errors.Is(err, ErrInvalidStorageCapacity) {
+		t.Errorf("expected %v, got %v", ErrInvalidStorageCapacity, err)
+	}
+
+	for _, hugePages := range []bool{false, true} {
+		s, err := MmapStorageFactory{HugePages: hugePages}.Make(1020)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		b := s.(*bitset)
+		if len(b.data) != 1024/bitsetDataSize || b.Capacity() != 1020 {
+			t.Errorf("expected %v words of capacity 1020, got %v, %v", 1024/bitsetDataSize, len(b.data), b.Capacity())
+		}
+		for _, w := range b.data {
+			if w != 0 {
+				t.Errorf("expected memory is zeroed")
+			}
+		}
+
+		s.Set(1019)
+		if !s.Get(1019) || s.Get(1018) {
+			t.Errorf("unexpected data")
+		}
+
+		r := s.(Releaser)
+		if err = r.Release(); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+		if err = r.Release(); err != nil {
+			t.Errorf("expected releasing twice is nil, got %v", err)
+		}
+		if s.Capacity() != 0 || s.Get(1019) {
+			t.Errorf("expected released storage is empty")
+		}
+	}
+}
+
+func TestMmapStorageFactory_ReleasedFilterReturnsEarly(t *testing.T) {
+	for _, m := range []IndexMapping{IndexMappingMultiplyShift, IndexMappingModulo} {
+		f := Must(WithCapacity(1000, 3), WithStorage(MmapStorageFactory{}), WithIndexMapping(m))
+		other := Must(WithCapacity(1000, 3), WithStorage(MmapStorageFactory{}), WithIndexMapping(m))
+		other.Add([]byte("a"))
+		if err := f.Storage().(Releaser).Release(); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+
+		f.Add([]byte("a"))
+		if f.Exists([]byte("a")) {
+			t.Errorf("expected released filter finds no items with %v", m)
+		}
+		if r := ExistsMany(f, [][]byte{[]byte("a"), []byte("b")}); r[0] || r[1] {
+			t.Errorf("expected ExistsMany of released filter finds no items with %v", m)
+		}
+		f.Storage().(BatchUnion).Union(other.Storage())
+		f.Storage().(BatchCopy).Copy(other.Storage())
+		f.Reset()
+		_ = other.Storage().(Releaser).Release()
+	}
+}
+
+func TestMmapStorageFactory_HugeFilter(t *testing.T) {
+	if ^uint(0)>>32 == 0 {
+		t.Skip("512 MB mapping does not fit the address space of 32-bit platforms")
+	}
+	f := Must(WithCapacity(1<<32-1, 3), WithStorage(MmapStorageFactory{HugePages: true}))
+	defer f.Storage().(Releaser).Release()
+
+	f.Add([]byte("anything"))
+	if !f.Exists([]byte("anything")) || f.Exists([]byte("not found")) {
+		t.Errorf("unexpected result of Exists")
+	}
+}
+
+func TestMmapStorageFactory_WorksWithHeapFilters(t *testing.T) {
+	a := Must(WithCapacity(4096, 3), WithStorage(MmapStorageFactory{}))
+	b := Must(WithCapacity(4096, 3))
+	a.Add([]byte("a"))
+	b.Add([]byte("b"))
+
+	if err := b.Union(a); err != nil || !b.Exists([]byte("a")) {
+		t.Errorf("expected heap filter could union a mmap filter, got %v", err)
+	}
+	if err := a.Union(b); err != nil || !a.Exists([]byte("b")) {
+		t.Errorf("expected mmap filter could union a heap filter, got %v", err)
+	}
+
+	data, _ := Marshal(a)
+	r, err := Unmarshal(data, WithStorage(MmapStorageFactory{}))
+	if err != nil || !r.Exists([]byte("a")) || !r.Exists([]byte("b")) {
+		t.Errorf("expected mmap filter could be serialized, got %v", err)
+	}
+
+	c, _ := a.Clone()
+	_ = a.Storage().(Releaser).Release()
+	if !c.Exists([]byte("a")) {
+		t.Errorf("expected Clone has its own memory")
+	}
+}
+
+func TestMmapStorageFactory_ConcurrentMergeSourceIsNotMapped(t *testing.T) {
+	a := Must(WithCapacity(4096, 3), WithStorage(MmapStorageFactory{}), WithConcurrency())
+	b := Must(WithCapacity(4096, 3), WithStorage(MmapStorageFactory{}), WithConcurrency())
+	defer a.Storage().(Releaser).Release()
+	defer b.Storage().(Releaser).Release()
+	b.Add([]byte("b"))
+
+	o, err := a.(*concurrentBloomFilter).stable(b)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if s, ok := o.Storage().(*bitset); !ok || s.release != nil {
+		t.Errorf("expected the merge source is copied into the heap")
+	}
+
+	if err := a.Union(b); err != nil || !a.Exists([]byte("b")) {
+		t.Errorf("expected union of mmap filters, got %v", err)
+	}
+	if err := a.Intersect(b); err != nil || !a.Exists([]byte("b")) {
+		t.Errorf("expected intersect of mmap filters, got %v", err)
+	}
+	if err := a.CopyFrom(b); err != nil || !a.Exists([]byte("b")) {
+		t.Errorf("expected copy of mmap filters, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package bf
+
+import "sync"
+
+/*
+Template hands out empty filters of the same Config and options from a
+sync.Pool, so many short-lived filters do not allocate a Storage every time. A
+filter given back by Put is Reset and must not be used afterwards. Filters
+with a Storage which implements Releaser are released by Put instead of pooled,
+the pool could drop filters at any time. Template is safe for concurrent use.
+*/
+type Template struct {
+	option   Option
+	capacity uint32
+	hasher   Hasher
+	pool     sync.Pool
+}
+
+// NewTemplate creates a Template of filters which New creates with the same
+// Config and options.
+func NewTemplate(config Config, opts ...OptionFunc) (*Template, error) {
+	o, err := newOption(config, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := newBloomFilter(o)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Template{option: o, capacity: f.storage.Capacity(), hasher: f.hasher}
+	t.Put(o.wrap(f))
+	return t, nil
+}
+
+// Get returns an empty filter from the pool or a new one if the pool is empty.
+func (t *Template) Get() (BloomFilter, error) {
+	if f, ok := t.pool.Get().(BloomFilter); ok {
+		return f, nil
+	}
+
+	f, err := newBloomFilter(t.option)
+	if err != nil {
+		return nil, err
+	}
+	return t.option.wrap(f), nil
+}
+
+// Put resets the filter and puts it into the pool, filters which could not be
+// made by the template are ignored.
+func (t *Template) Put(f BloomFilter) {
+	if !t.owns(f) {
+		return
+	}
+
+	if r, ok := f.Storage().(Releaser); ok && offHeap(f.Storage()) {
+		_ = r.Release()
+		return
+	}
+
+	f.Reset()
+	t.pool.Put(f)
+}
+
+func (t *Template) owns(f BloomFilter) bool {
+	var b *bloomFilter
+	switch v := f.(type) {
+	case *bloomFilter:
+		b = v
+	case *concurrentBloomFilter:
+		b = v.filter
+	default:
+		return false
+	}
+
+	_, concurrent := f.(*concurrentBloomFilter)
+	return concurrent == t.option.concurrent &&
+		b.option.distinctCount == t.option.distinctCount &&
+		b.option.mapping == t.option.mapping &&
+		b.storage.Capacity() == t.capacity &&
+		b.hasher.Equals(t.hasher)
+}
+
+// offHeap reports whether the Storage holds memory outside the Go heap, a
+// bitset implements Releaser but only the one made by MmapStorageFactory does.
+func offHeap(s Storage) bool {
+	if b, ok := s.(*bitset); ok {
+		return b.release != nil
+	}
+	_, ok := s.(Releaser)
+	return ok
+}
@@ -0,0 +1,120 @@
+package bf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestNewTemplate_ReturnsErrIfArgumentsAreInvalid(t *testing.T) {
+	if _, err := NewTemplate(nil); !errors.Is(err, ErrNilConfig) {
+		t.Errorf("expected %v, got %v", ErrNilConfig, err)
+	}
+
+	expected := errors.New("whatever")
+	if _, err := NewTemplate(WithCapacity(100, 3), WithStorage(&stubStorageFactory{err: expected})); !errors.Is(err, expected) {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestTemplate_GetAndPut(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []OptionFunc
+	}{
+		{name: "bitset"},
+		{name: "distinct count", opts: []OptionFunc{WithDistinctCount(), WithFNV()}},
+		{name: "concurrent", opts: []OptionFunc{WithConcurrency()}},
+		{name: "mmap", opts: []OptionFunc{WithStorage(MmapStorageFactory{})}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := NewTemplate(WithCapacity(4096, 3), tc.opts...)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			expected := Must(WithCapacity(4096, 3), tc.opts...)
+
+			for round := 0; round < 3; round++ {
+				f, err := tpl.Get()
+				if err != nil {
+					t.Fatalf("expected nil, got %v", err)
+				}
+				if err = Compatible(f, expected); err != nil {
+					t.Fatalf("expected compatible filter, got %v", err)
+				}
+				if fmt.Sprintf("%T", f) != fmt.Sprintf("%T", expected) {
+					t.Errorf("expected %T, got %T", expected, f)
+				}
+				if f.Count() != 0 || countBits(f.Storage()) != 0 {
+					t.Fatalf("expected empty filter, got count %v", f.Count())
+				}
+
+				f.Add([]byte(fmt.Sprintf("item-%d", round)))
+				tpl.Put(f)
+			}
+		})
+	}
+}
+
+func TestTemplate_PutReleasesOffHeapStorage(t *testing.T) {
+	tpl, _ := NewTemplate(WithCapacity(4096, 3), WithStorage(MmapStorageFactory{}))
+	f, _ := tpl.Get()
+	tpl.Put(f)
+	if f.Storage().Capacity() != 0 {
+		t.Errorf("expected the storage is released")
+	}
+
+	tpl, _ = NewTemplate(WithCapacity(4096, 3))
+	f, _ = tpl.Get()
+	tpl.Put(f)
+	if f.Storage().Capacity() != 4096 {
+		t.Errorf("expected the storage is pooled")
+	}
+}
+
+func TestTemplate_PutIgnoresOtherFilters(t *testing.T) {
+	tpl, _ := NewTemplate(WithCapacity(4096, 3))
+
+	others := []BloomFilter{
+		nil,
+		Must(WithCapacity(2048, 3)),
+		Must(WithCapacity(4096, 3), WithFNV()),
+		Must(WithCapacity(4096, 3), WithConcurrency()),
+		Must(WithCapacity(4096, 3), WithDistinctCount()),
+		Must(WithCapacity(4096, 3), WithIndexMapping(IndexMappingModulo)),
+		wrappedBloomFilter{Must(WithCapacity(4096, 3))},
+	}
+	for _, other := range others {
+		if other != nil {
+			other.Add([]byte("item"))
+		}
+		tpl.Put(other)
+		if other != nil && !other.Exists([]byte("item")) {
+			t.Errorf("expected %T is not reset", other)
+		}
+	}
+}
+
+func TestTemplate_ConcurrentUse(t *testing.T) {
+	tpl, _ := NewTemplate(WithCapacity(4096, 3))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				f, _ := tpl.Get()
+				if f.Count() != 0 {
+					t.Errorf("expected empty filter")
+				}
+				f.Add([]byte(fmt.Sprintf("item-%d-%d", i, j)))
+				tpl.Put(f)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,132 @@
+package bf
+
+import (
+	"encoding"
+	"encoding/binary"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// Encoder converts a value into bytes which are added into or checked by a
+// BloomFilter, the same value must always be encoded to the same bytes.
+type Encoder[T any] func(value T) ([]byte, error)
+
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+/*
+Typed wraps a BloomFilter to add and check values of type T which are encoded
+by an Encoder, built-in encoders are StringEncoder, IntegerEncoder and
+BinaryMarshalerEncoder. Typed is safe for concurrent use if the filter is.
+
+Add and Exists cannot return an error of the Encoder, the last error could be
+retrieved by Err, a value which cannot be encoded is not added and does not
+exist.
+*/
+type Typed[T any] struct {
+	filter  BloomFilter
+	encoder Encoder[T]
+	mu      sync.Mutex
+	err     error
+}
+
+func NewTyped[T any](filter BloomFilter, encoder Encoder[T]) (*Typed[T], error) {
+	if filter == nil {
+		return nil, ErrNilBloomFilter
+	}
+	if encoder == nil {
+		return nil, ErrNilEncoder
+	}
+	return &Typed[T]{filter: filter, encoder: encoder}, nil
+}
+
+func (t *Typed[T]) Add(value T) {
+	if data, ok := t.encode(value); ok {
+		t.filter.Add(data)
+	}
+}
+
+func (t *Typed[T]) Exists(value T) bool {
+	data, ok := t.encode(value)
+	return ok && t.filter.Exists(data)
+}
+
+func (t *Typed[T]) TestAndAdd(value T) bool {
+	data, ok := t.encode(value)
+	return ok && t.filter.TestAndAdd(data)
+}
+
+// Filter returns the wrapped BloomFilter.
+func (t *Typed[T]) Filter() BloomFilter {
+	return t.filter
+}
+
+// Err returns the last error of the Encoder.
+func (t *Typed[T]) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.err
+}
+
+func (t *Typed[T]) encode(value T) ([]byte, bool) {
+	data, err := t.encoder(value)
+	if err != nil {
+		t.mu.Lock()
+		t.err = err
+		t.mu.Unlock()
+		return nil, false
+	}
+	return data, true
+}
+
+/*
+StringEncoder encodes a string to its bytes without copying them, the result
+is the same as []byte(value) but shares the memory of the string. Hashers must
+not modify or keep the input, the built-in hashers do not.
+*/
+func StringEncoder[T ~string]() Encoder[T] {
+	return func(value T) ([]byte, error) {
+		// a string header followed by its length is the header of a slice whose
+		// capacity is its length
+		s := string(value)
+		return *(*[]byte)(unsafe.Pointer(&struct {
+			string
+			int
+		}{s, len(s)})), nil
+	}
+}
+
+/*
+IntegerEncoder encodes an integer in little-endian with a fixed width of its
+type. int, uint and uintptr are always encoded in 8 bytes so filters are the
+same on 32-bit and 64-bit platforms.
+*/
+func IntegerEncoder[T Integer]() Encoder[T] {
+	var zero T
+	width := 8
+	switch reflect.TypeOf(zero).Kind() {
+	case reflect.Int8, reflect.Uint8:
+		width = 1
+	case reflect.Int16, reflect.Uint16:
+		width = 2
+	case reflect.Int32, reflect.Uint32:
+		width = 4
+	}
+
+	return func(value T) ([]byte, error) {
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], uint64(value))
+		return buf[:width], nil
+	}
+}
+
+// BinaryMarshalerEncoder encodes a value by its MarshalBinary method.
+func BinaryMarshalerEncoder[T encoding.BinaryMarshaler]() Encoder[T] {
+	return func(value T) ([]byte, error) {
+		return value.MarshalBinary()
+	}
+}
@@ -0,0 +1,131 @@
+package bf
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalBinary() ([]byte, error) {
+	return nil, errors.New("failed")
+}
+
+func TestNewTyped_ReturnsErrIfParamsAreNil(t *testing.T) {
+	if _, err := NewTyped[string](nil, StringEncoder[string]()); !errors.Is(err, ErrNilBloomFilter) {
+		t.Errorf("expected %v, got %v", ErrNilBloomFilter, err)
+	}
+	if _, err := NewTyped[string](Must(WithCapacity(1024, 3)), nil); !errors.Is(err, ErrNilEncoder) {
+		t.Errorf("expected %v, got %v", ErrNilEncoder, err)
+	}
+}
+
+func TestTyped_String(t *testing.T) {
+	type name string
+	f := Must(WithCapacity(1024, 3))
+	typed, _ := NewTyped(f, StringEncoder[name]())
+	typed.Add("anything")
+
+	if !typed.TestAndAdd("anything") || typed.TestAndAdd("new") || !typed.Exists("new") {
+		t.Errorf("unexpected result of TestAndAdd")
+	}
+	if !typed.Exists("anything") || typed.Exists("not found") {
+		t.Errorf("unexpected result of Exists")
+	}
+	if !f.Exists([]byte("anything")) {
+		t.Errorf("expected a string is encoded to its bytes")
+	}
+	if typed.Filter() != f {
+		t.Errorf("expected Filter returns the given filter")
+	}
+}
+
+func TestStringEncoder_DoesNotCopyBytes(t *testing.T) {
+	encoder := StringEncoder[string]()
+	value := strings.Repeat("a", 3)
+
+	data, _ := encoder(value)
+	if string(data) != value || cap(data) != len(value) {
+		t.Errorf("expected %v with capacity %v, got %v with capacity %v", value, len(value), string(data), cap(data))
+	}
+	if allocs := testing.AllocsPerRun(100, func() { _, _ = encoder(value) }); allocs != 0 {
+		t.Errorf("expected no allocation, got %v", allocs)
+	}
+	if data, _ := encoder(""); len(data) != 0 {
+		t.Errorf("expected empty bytes, got %v", data)
+	}
+}
+
+func TestIntegerEncoder(t *testing.T) {
+	cases := []struct {
+		name     string
+		actual   []byte
+		expected []byte
+	}{
+		{name: "int8", actual: mustEncode(IntegerEncoder[int8](), -2), expected: []byte{0xfe}},
+		{name: "uint16", actual: mustEncode(IntegerEncoder[uint16](), 0x0102), expected: []byte{2, 1}},
+		{name: "int32", actual: mustEncode(IntegerEncoder[int32](), 0x01020304), expected: []byte{4, 3, 2, 1}},
+		{name: "int", actual: mustEncode(IntegerEncoder[int](), 1), expected: []byte{1, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "uint64", actual: mustEncode(IntegerEncoder[uint64](), 1<<56), expected: []byte{0, 0, 0, 0, 0, 0, 0, 1}},
+		{name: "duration", actual: mustEncode(IntegerEncoder[time.Duration](), 256), expected: []byte{0, 1, 0, 0, 0, 0, 0, 0}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !isArrayEquals(tc.actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, tc.actual)
+			}
+		})
+	}
+}
+
+func TestTyped_Integer(t *testing.T) {
+	typed, _ := NewTyped(Must(WithAccuracy(0.01, 1000)), IntegerEncoder[int]())
+	for i := 0; i < 1000; i++ {
+		typed.Add(i)
+	}
+	for i := 0; i < 1000; i++ {
+		if !typed.Exists(i) {
+			t.Errorf("expected %v exists", i)
+		}
+	}
+}
+
+func TestTyped_BinaryMarshaler(t *testing.T) {
+	typed, _ := NewTyped(Must(WithCapacity(1024, 3)), BinaryMarshalerEncoder[netip.Addr]())
+	typed.Add(netip.MustParseAddr("10.0.0.1"))
+
+	if !typed.Exists(netip.MustParseAddr("10.0.0.1")) || typed.Exists(netip.MustParseAddr("10.0.0.2")) {
+		t.Errorf("unexpected result of Exists")
+	}
+	if typed.Err() != nil {
+		t.Errorf("expected nil, got %v", typed.Err())
+	}
+}
+
+func TestTyped_RecordsErrOfEncoder(t *testing.T) {
+	f := Must(WithCapacity(1024, 3))
+	typed, _ := NewTyped(f, BinaryMarshalerEncoder[failingMarshaler]())
+	typed.Add(failingMarshaler{})
+
+	if f.Count() != 0 {
+		t.Errorf("expected a value which cannot be encoded is not added")
+	}
+	if typed.Exists(failingMarshaler{}) {
+		t.Errorf("expected a value which cannot be encoded does not exist")
+	}
+	if typed.Err() == nil || typed.Err().Error() != "failed" {
+		t.Errorf("expected error of Encoder, got %v", typed.Err())
+	}
+}
+
+func mustEncode[T any](e Encoder[T], value T) []byte {
+	data, err := e(value)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
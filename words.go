@@ -0,0 +1,95 @@
+package bf
+
+import "math/bits"
+
+// Word operations apply to len(dst) words, src must not be shorter. They are
+// implemented by AVX2 on amd64 and by the generic unrolled loops below
+// elsewhere or with the purego build tag.
+
+func andWordsGeneric(dst, src []uint64) {
+	src = src[:len(dst)]
+	i := 0
+	for ; i+4 <= len(dst); i += 4 {
+		dst[i] &= src[i]
+		dst[i+1] &= src[i+1]
+		dst[i+2] &= src[i+2]
+		dst[i+3] &= src[i+3]
+	}
+	for ; i < len(dst); i++ {
+		dst[i] &= src[i]
+	}
+}
+
+func orWordsGeneric(dst, src []uint64) {
+	src = src[:len(dst)]
+	i := 0
+	for ; i+4 <= len(dst); i += 4 {
+		dst[i] |= src[i]
+		dst[i+1] |= src[i+1]
+		dst[i+2] |= src[i+2]
+		dst[i+3] |= src[i+3]
+	}
+	for ; i < len(dst); i++ {
+		dst[i] |= src[i]
+	}
+}
+
+func andNotWordsGeneric(dst, src []uint64) {
+	src = src[:len(dst)]
+	i := 0
+	for ; i+4 <= len(dst); i += 4 {
+		dst[i] &^= src[i]
+		dst[i+1] &^= src[i+1]
+		dst[i+2] &^= src[i+2]
+		dst[i+3] &^= src[i+3]
+	}
+	for ; i < len(dst); i++ {
+		dst[i] &^= src[i]
+	}
+}
+
+func xorWordsGeneric(dst, src []uint64) {
+	src = src[:len(dst)]
+	i := 0
+	for ; i+4 <= len(dst); i += 4 {
+		dst[i] ^= src[i]
+		dst[i+1] ^= src[i+1]
+		dst[i+2] ^= src[i+2]
+		dst[i+3] ^= src[i+3]
+	}
+	for ; i < len(dst); i++ {
+		dst[i] ^= src[i]
+	}
+}
+
+func popcountWordsGeneric(data []uint64) int {
+	var c0, c1, c2, c3 int
+	i := 0
+	for ; i+4 <= len(data); i += 4 {
+		c0 += bits.OnesCount64(data[i])
+		c1 += bits.OnesCount64(data[i+1])
+		c2 += bits.OnesCount64(data[i+2])
+		c3 += bits.OnesCount64(data[i+3])
+	}
+	for ; i < len(data); i++ {
+		c0 += bits.OnesCount64(data[i])
+	}
+	return c0 + c1 + c2 + c3
+}
+
+// orCountWordsGeneric counts set bits of a | b without changing them.
+func orCountWordsGeneric(a, b []uint64) int {
+	b = b[:len(a)]
+	var c0, c1, c2, c3 int
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		c0 += bits.OnesCount64(a[i] | b[i])
+		c1 += bits.OnesCount64(a[i+1] | b[i+1])
+		c2 += bits.OnesCount64(a[i+2] | b[i+2])
+		c3 += bits.OnesCount64(a[i+3] | b[i+3])
+	}
+	for ; i < len(a); i++ {
+		c0 += bits.OnesCount64(a[i] | b[i])
+	}
+	return c0 + c1 + c2 + c3
+}
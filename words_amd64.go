@@ -0,0 +1,101 @@
+//go:build amd64 && !purego
+
+package bf
+
+// avx2Block is the number of words handled by one iteration of AVX2 loops,
+// the remaining words are handled by the generic loops.
+const avx2Block = 16
+
+var hasAVX2 = detectAVX2()
+
+func detectAVX2() bool {
+	maxID, _, _, _ := cpuid(0, 0)
+	if maxID < 7 {
+		return false
+	}
+
+	_, _, ecx, _ := cpuid(1, 0)
+	osxsave, avx := ecx&(1<<27) != 0, ecx&(1<<28) != 0
+	if !osxsave || !avx {
+		return false
+	}
+
+	// the operating system must save YMM registers
+	if eax, _ := xgetbv(); eax&6 != 6 {
+		return false
+	}
+
+	_, ebx, _, _ := cpuid(7, 0)
+	return ebx&(1<<5) != 0
+}
+
+//go:noescape
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+//go:noescape
+func xgetbv() (eax, edx uint32)
+
+//go:noescape
+func andWordsAVX2(dst, src []uint64)
+
+//go:noescape
+func orWordsAVX2(dst, src []uint64)
+
+//go:noescape
+func andNotWordsAVX2(dst, src []uint64)
+
+//go:noescape
+func xorWordsAVX2(dst, src []uint64)
+
+//go:noescape
+func popcountWordsAVX2(data []uint64) int
+
+//go:noescape
+func orCountWordsAVX2(a, b []uint64) int
+
+// binaryWords applies the AVX2 op to full blocks and the generic op to the
+// remaining words.
+func binaryWords(dst, src []uint64, avx2, generic func(dst, src []uint64)) {
+	src = src[:len(dst)]
+	n := len(dst)
+	if hasAVX2 {
+		n -= n % avx2Block
+		avx2(dst[:n], src[:n])
+	} else {
+		n = 0
+	}
+	generic(dst[n:], src[n:])
+}
+
+func andWords(dst, src []uint64) {
+	binaryWords(dst, src, andWordsAVX2, andWordsGeneric)
+}
+
+func orWords(dst, src []uint64) {
+	binaryWords(dst, src, orWordsAVX2, orWordsGeneric)
+}
+
+func andNotWords(dst, src []uint64) {
+	binaryWords(dst, src, andNotWordsAVX2, andNotWordsGeneric)
+}
+
+func xorWords(dst, src []uint64) {
+	binaryWords(dst, src, xorWordsAVX2, xorWordsGeneric)
+}
+
+func popcountWords(data []uint64) int {
+	if !hasAVX2 {
+		return popcountWordsGeneric(data)
+	}
+	n := len(data) - len(data)%avx2Block
+	return popcountWordsAVX2(data[:n]) + popcountWordsGeneric(data[n:])
+}
+
+func orCountWords(a, b []uint64) uint32 {
+	if !hasAVX2 {
+		return uint32(orCountWordsGeneric(a, b))
+	}
+	b = b[:len(a)]
+	n := len(a) - len(a)%avx2Block
+	return uint32(orCountWordsAVX2(a[:n], b[:n]) + orCountWordsGeneric(a[n:], b[n:]))
+}
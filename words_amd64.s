@@ -0,0 +1,285 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// popcountTable holds number of set bits of every nibble, twice for both lanes.
+DATA popcountTable<>+0x00(SB)/8, $0x0302020102010100
+DATA popcountTable<>+0x08(SB)/8, $0x0403030203020201
+DATA popcountTable<>+0x10(SB)/8, $0x0302020102010100
+DATA popcountTable<>+0x18(SB)/8, $0x0403030203020201
+GLOBL popcountTable<>(SB), RODATA|NOPTR, $32
+
+DATA lowNibbles<>+0x00(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA lowNibbles<>+0x08(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA lowNibbles<>+0x10(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA lowNibbles<>+0x18(SB)/8, $0x0f0f0f0f0f0f0f0f
+GLOBL lowNibbles<>(SB), RODATA|NOPTR, $32
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
+// func xgetbv() (eax, edx uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	MOVL $0, CX
+	XGETBV
+	MOVL AX, eax+0(FP)
+	MOVL DX, edx+4(FP)
+	RET
+
+// Binary operations handle len(dst)/16 blocks of 16 words, VPANDN computes
+// NOT src AND dst.
+
+// func andWordsAVX2(dst, src []uint64)
+TEXT ·andWordsAVX2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ dst_len+8(FP), CX
+	MOVQ src_base+24(FP), SI
+	SHRQ $4, CX
+	JZ   andWordsAVX2_done
+
+andWordsAVX2_loop:
+	VMOVDQU (SI), Y0
+	VMOVDQU 32(SI), Y1
+	VMOVDQU 64(SI), Y2
+	VMOVDQU 96(SI), Y3
+	VPAND   (DI), Y0, Y0
+	VPAND   32(DI), Y1, Y1
+	VPAND   64(DI), Y2, Y2
+	VPAND   96(DI), Y3, Y3
+	VMOVDQU Y0, (DI)
+	VMOVDQU Y1, 32(DI)
+	VMOVDQU Y2, 64(DI)
+	VMOVDQU Y3, 96(DI)
+	ADDQ    $128, SI
+	ADDQ    $128, DI
+	DECQ    CX
+	JNZ     andWordsAVX2_loop
+
+andWordsAVX2_done:
+	VZEROUPPER
+	RET
+
+// func orWordsAVX2(dst, src []uint64)
+TEXT ·orWordsAVX2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ dst_len+8(FP), CX
+	MOVQ src_base+24(FP), SI
+	SHRQ $4, CX
+	JZ   orWordsAVX2_done
+
+orWordsAVX2_loop:
+	VMOVDQU (SI), Y0
+	VMOVDQU 32(SI), Y1
+	VMOVDQU 64(SI), Y2
+	VMOVDQU 96(SI), Y3
+	VPOR    (DI), Y0, Y0
+	VPOR    32(DI), Y1, Y1
+	VPOR    64(DI), Y2, Y2
+	VPOR    96(DI), Y3, Y3
+	VMOVDQU Y0, (DI)
+	VMOVDQU Y1, 32(DI)
+	VMOVDQU Y2, 64(DI)
+	VMOVDQU Y3, 96(DI)
+	ADDQ    $128, SI
+	ADDQ    $128, DI
+	DECQ    CX
+	JNZ     orWordsAVX2_loop
+
+orWordsAVX2_done:
+	VZEROUPPER
+	RET
+
+// func andNotWordsAVX2(dst, src []uint64)
+TEXT ·andNotWordsAVX2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ dst_len+8(FP), CX
+	MOVQ src_base+24(FP), SI
+	SHRQ $4, CX
+	JZ   andNotWordsAVX2_done
+
+andNotWordsAVX2_loop:
+	VMOVDQU (SI), Y0
+	VMOVDQU 32(SI), Y1
+	VMOVDQU 64(SI), Y2
+	VMOVDQU 96(SI), Y3
+	VPANDN  (DI), Y0, Y0
+	VPANDN  32(DI), Y1, Y1
+	VPANDN  64(DI), Y2, Y2
+	VPANDN  96(DI), Y3, Y3
+	VMOVDQU Y0, (DI)
+	VMOVDQU Y1, 32(DI)
+	VMOVDQU Y2, 64(DI)
+	VMOVDQU Y3, 96(DI)
+	ADDQ    $128, SI
+	ADDQ    $128, DI
+	DECQ    CX
+	JNZ     andNotWordsAVX2_loop
+
+andNotWordsAVX2_done:
+	VZEROUPPER
+	RET
+
+// func xorWordsAVX2(dst, src []uint64)
+TEXT ·xorWordsAVX2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ dst_len+8(FP), CX
+	MOVQ src_base+24(FP), SI
+	SHRQ $4, CX
+	JZ   xorWordsAVX2_done
+
+xorWordsAVX2_loop:
+	VMOVDQU (SI), Y0
+	VMOVDQU 32(SI), Y1
+	VMOVDQU 64(SI), Y2
+	VMOVDQU 96(SI), Y3
+	VPXOR   (DI), Y0, Y0
+	VPXOR   32(DI), Y1, Y1
+	VPXOR   64(DI), Y2, Y2
+	VPXOR   96(DI), Y3, Y3
+	VMOVDQU Y0, (DI)
+	VMOVDQU Y1, 32(DI)
+	VMOVDQU Y2, 64(DI)
+	VMOVDQU Y3, 96(DI)
+	ADDQ    $128, SI
+	ADDQ    $128, DI
+	DECQ    CX
+	JNZ     xorWordsAVX2_loop
+
+xorWordsAVX2_done:
+	VZEROUPPER
+	RET
+
+// Counting uses a nibble lookup by VPSHUFB, byte counts of 16 words are at most
+// 8*16 so they do not overflow before VPSADBW sums them into quad words.
+
+// func popcountWordsAVX2(data []uint64) int
+TEXT ·popcountWordsAVX2(SB), NOSPLIT, $0-32
+	MOVQ data_base+0(FP), SI
+	MOVQ data_len+8(FP), CX
+	SHRQ $4, CX
+	VPXOR   Y4, Y4, Y4
+	VPXOR   Y5, Y5, Y5
+	JZ      popcountWordsAVX2_sum
+	VMOVDQU popcountTable<>(SB), Y6
+	VMOVDQU lowNibbles<>(SB), Y7
+
+popcountWordsAVX2_loop:
+	VMOVDQU (SI), Y0
+	VMOVDQU 32(SI), Y1
+	VMOVDQU 64(SI), Y2
+	VMOVDQU 96(SI), Y3
+	VPSRLW  $4, Y0, Y9
+	VPAND   Y7, Y0, Y0
+	VPAND   Y7, Y9, Y9
+	VPSHUFB Y0, Y6, Y0
+	VPSHUFB Y9, Y6, Y9
+	VPADDB  Y9, Y0, Y0
+	VPSRLW  $4, Y1, Y9
+	VPAND   Y7, Y1, Y1
+	VPAND   Y7, Y9, Y9
+	VPSHUFB Y1, Y6, Y1
+	VPSHUFB Y9, Y6, Y9
+	VPADDB  Y9, Y1, Y1
+	VPSRLW  $4, Y2, Y9
+	VPAND   Y7, Y2, Y2
+	VPAND   Y7, Y9, Y9
+	VPSHUFB Y2, Y6, Y2
+	VPSHUFB Y9, Y6, Y9
+	VPADDB  Y9, Y2, Y2
+	VPSRLW  $4, Y3, Y9
+	VPAND   Y7, Y3, Y3
+	VPAND   Y7, Y9, Y9
+	VPSHUFB Y3, Y6, Y3
+	VPSHUFB Y9, Y6, Y9
+	VPADDB  Y9, Y3, Y3
+	VPADDB  Y1, Y0, Y0
+	VPADDB  Y3, Y2, Y2
+	VPADDB  Y2, Y0, Y0
+	VPSADBW Y5, Y0, Y0
+	VPADDQ  Y0, Y4, Y4
+	ADDQ    $128, SI
+	DECQ    CX
+	JNZ     popcountWordsAVX2_loop
+
+popcountWordsAVX2_sum:
+	VEXTRACTI128 $1, Y4, X0
+	VPADDQ       X0, X4, X4
+	VPSHUFD      $0x4e, X4, X0
+	VPADDQ       X0, X4, X4
+	VMOVQ        X4, AX
+	MOVQ         AX, ret+24(FP)
+	VZEROUPPER
+	RET
+
+// func orCountWordsAVX2(a, b []uint64) int
+TEXT ·orCountWordsAVX2(SB), NOSPLIT, $0-56
+	MOVQ a_base+0(FP), SI
+	MOVQ a_len+8(FP), CX
+	MOVQ b_base+24(FP), DI
+	SHRQ $4, CX
+	VPXOR   Y4, Y4, Y4
+	VPXOR   Y5, Y5, Y5
+	JZ      orCountWordsAVX2_sum
+	VMOVDQU popcountTable<>(SB), Y6
+	VMOVDQU lowNibbles<>(SB), Y7
+
+orCountWordsAVX2_loop:
+	VMOVDQU (SI), Y0
+	VMOVDQU 32(SI), Y1
+	VMOVDQU 64(SI), Y2
+	VMOVDQU 96(SI), Y3
+	VPOR    (DI), Y0, Y0
+	VPOR    32(DI), Y1, Y1
+	VPOR    64(DI), Y2, Y2
+	VPOR    96(DI), Y3, Y3
+	VPSRLW  $4, Y0, Y9
+	VPAND   Y7, Y0, Y0
+	VPAND   Y7, Y9, Y9
+	VPSHUFB Y0, Y6, Y0
+	VPSHUFB Y9, Y6, Y9
+	VPADDB  Y9, Y0, Y0
+	VPSRLW  $4, Y1, Y9
+	VPAND   Y7, Y1, Y1
+	VPAND   Y7, Y9, Y9
+	VPSHUFB Y1, Y6, Y1
+	VPSHUFB Y9, Y6, Y9
+	VPADDB  Y9, Y1, Y1
+	VPSRLW  $4, Y2, Y9
+	VPAND   Y7, Y2, Y2
+	VPAND   Y7, Y9, Y9
+	VPSHUFB Y2, Y6, Y2
+	VPSHUFB Y9, Y6, Y9
+	VPADDB  Y9, Y2, Y2
+	VPSRLW  $4, Y3, Y9
+	VPAND   Y7, Y3, Y3
+	VPAND   Y7, Y9, Y9
+	VPSHUFB Y3, Y6, Y3
+	VPSHUFB Y9, Y6, Y9
+	VPADDB  Y9, Y3, Y3
+	VPADDB  Y1, Y0, Y0
+	VPADDB  Y3, Y2, Y2
+	VPADDB  Y2, Y0, Y0
+	VPSADBW Y5, Y0, Y0
+	VPADDQ  Y0, Y4, Y4
+	ADDQ    $128, SI
+	ADDQ    $128, DI
+	DECQ    CX
+	JNZ     orCountWordsAVX2_loop
+
+orCountWordsAVX2_sum:
+	VEXTRACTI128 $1, Y4, X0
+	VPADDQ       X0, X4, X4
+	VPSHUFD      $0x4e, X4, X0
+	VPADDQ       X0, X4, X4
+	VMOVQ        X4, AX
+	MOVQ         AX, ret+48(FP)
+	VZEROUPPER
+	RET
@@ -0,0 +1,12 @@
+//go:build amd64 && !purego
+
+package bf
+
+import "testing"
+
+func TestWords_WithoutAVX2(t *testing.T) {
+	defer func(v bool) { hasAVX2 = v }(hasAVX2)
+	hasAVX2 = false
+
+	TestWords_AllLengths(t)
+}
@@ -0,0 +1,27 @@
+//go:build !amd64 || purego
+
+package bf
+
+func andWords(dst, src []uint64) {
+	andWordsGeneric(dst, src)
+}
+
+func orWords(dst, src []uint64) {
+	orWordsGeneric(dst, src)
+}
+
+func andNotWords(dst, src []uint64) {
+	andNotWordsGeneric(dst, src)
+}
+
+func xorWords(dst, src []uint64) {
+	xorWordsGeneric(dst, src)
+}
+
+func popcountWords(data []uint64) int {
+	return popcountWordsGeneric(data)
+}
+
+func orCountWords(a, b []uint64) uint32 {
+	return uint32(orCountWordsGeneric(a, b))
+}
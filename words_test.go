@@ -0,0 +1,88 @@
+package bf
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+func wordsFromBytes(data []byte) []uint64 {
+	result := make([]uint64, len(data)/8)
+	for i := range result {
+		result[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+	return result
+}
+
+func assertWordsAreEquivalent(t *testing.T, a, b []uint64) {
+	t.Helper()
+	b = b[:len(a)]
+
+	ops := []struct {
+		name              string
+		optimized, simple func(dst, src []uint64)
+		naive             func(x, y uint64) uint64
+	}{
+		{name: "and", optimized: andWords, simple: andWordsGeneric, naive: func(x, y uint64) uint64 { return x & y }},
+		{name: "or", optimized: orWords, simple: orWordsGeneric, naive: func(x, y uint64) uint64 { return x | y }},
+		{name: "and not", optimized: andNotWords, simple: andNotWordsGeneric, naive: func(x, y uint64) uint64 { return x &^ y }},
+		{name: "xor", optimized: xorWords, simple: xorWordsGeneric, naive: func(x, y uint64) uint64 { return x ^ y }},
+	}
+	for _, op := range ops {
+		optimized := append([]uint64{}, a...)
+		simple := append([]uint64{}, a...)
+		op.optimized(optimized, b)
+		op.simple(simple, b)
+		for i := range a {
+			expected := op.naive(a[i], b[i])
+			if optimized[i] != expected || simple[i] != expected {
+				t.Fatalf("%v of %v words: expected %x at %v, got %x and %x", op.name, len(a), expected, i, optimized[i], simple[i])
+			}
+		}
+	}
+
+	var count, orCount int
+	for i := range a {
+		count += bits.OnesCount64(a[i])
+		orCount += bits.OnesCount64(a[i] | b[i])
+	}
+	if popcountWords(a) != count || popcountWordsGeneric(a) != count {
+		t.Fatalf("popcount of %v words: expected %v, got %v and %v", len(a), count, popcountWords(a), popcountWordsGeneric(a))
+	}
+	if int(orCountWords(a, b)) != orCount || orCountWordsGeneric(a, b) != orCount {
+		t.Fatalf("or count of %v words: expected %v, got %v and %v", len(a), orCount, orCountWords(a, b), orCountWordsGeneric(a, b))
+	}
+}
+
+func TestWords_AllLengths(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n <= 100; n++ {
+		a, b := make([]uint64, n), make([]uint64, n+1)
+		for i := range a {
+			a[i] = r.Uint64()
+		}
+		for i := range b {
+			b[i] = r.Uint64()
+		}
+		assertWordsAreEquivalent(t, a, b)
+	}
+
+	full := make([]uint64, 48)
+	for i := range full {
+		full[i] = ^uint64(0)
+	}
+	assertWordsAreEquivalent(t, full, full)
+}
+
+func FuzzWords(f *testing.F) {
+	f.Add([]byte{1, 2, 3}, []byte{4, 5, 6})
+	f.Add(make([]byte, 8*17), make([]byte, 8*17))
+	f.Fuzz(func(t *testing.T, a, b []byte) {
+		wa, wb := wordsFromBytes(a), wordsFromBytes(b)
+		if len(wb) < len(wa) {
+			wa = wa[:len(wb)]
+		}
+		assertWordsAreEquivalent(t, wa, wb)
+	})
+}